@@ -1,7 +1,10 @@
 package jsc
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -32,6 +35,50 @@ func ActionRequest(baseURL string, resourceType, id string, action string) (*htt
 		return nil, jsh.SpecificationError("Action specifier cannot be empty for an Action request type")
 	}
 
+	return MethodActionRequest("GET", baseURL, resourceType, id, action, nil)
+}
+
+/*
+MethodAction performs an outbound <method> /resource/:id/action request, or a
+<method> /resource/action request if id is empty. The document is sent as the
+request body unless it is nil:
+
+	reason := &jsh.Document{Meta: map[string]interface{}{"reason": "duplicate"}}
+	// does POST http://apiserver/orders/1/cancel
+	doc, resp, err := jsc.MethodAction("POST", "http://apiserver", "orders", "1", "cancel", reason)
+*/
+func MethodAction(
+	method string,
+	baseURL string,
+	resourceType string,
+	id string,
+	action string,
+	document *jsh.Document,
+) (*jsh.Document, *http.Response, error) {
+	request, err := MethodActionRequest(method, baseURL, resourceType, id, action, document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return Do(request, jsh.ObjectMode)
+}
+
+/*
+MethodActionRequest returns a fully formatted JSONAPI Action request using the
+given method and optional request document, see MethodAction.
+*/
+func MethodActionRequest(
+	method string,
+	baseURL string,
+	resourceType string,
+	id string,
+	action string,
+	document *jsh.Document,
+) (*http.Request, error) {
+	if action == "" {
+		return nil, jsh.SpecificationError("Action specifier cannot be empty for an Action request type")
+	}
+
 	u, urlErr := url.Parse(baseURL)
 	if urlErr != nil {
 		return nil, jsh.ISE(fmt.Sprintf("Error parsing URL: %s", urlErr.Error()))
@@ -46,5 +93,15 @@ func ActionRequest(baseURL string, resourceType, id string, action string) (*htt
 
 	u.Path = strings.Join([]string{u.Path, action}, "/")
 
-	return NewRequest("GET", u.String(), nil)
+	var body io.Reader
+	if document != nil {
+		content, jsonErr := json.Marshal(document)
+		if jsonErr != nil {
+			return nil, fmt.Errorf("Unable to prepare JSON content: %s", jsonErr.Error())
+		}
+
+		body = bytes.NewReader(content)
+	}
+
+	return NewRequest(strings.ToUpper(method), u.String(), body)
 }
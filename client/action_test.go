@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/derekdowling/go-json-spec-handler"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -24,5 +25,27 @@ func TestAction(t *testing.T) {
 			So(resp.StatusCode, ShouldEqual, http.StatusOK)
 			So(doc, ShouldNotBeEmpty)
 		})
+
+		Convey("->MethodAction()", func() {
+
+			Convey("should send the request document", func() {
+				request := &jsh.Document{Meta: map[string]interface{}{"reason": "duplicate"}}
+
+				doc, resp, err := MethodAction("post", baseURL, "tests", "1", "cancel", request)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+
+				attributes := map[string]string{}
+				So(doc.First().Unmarshal("tests", &attributes), ShouldBeEmpty)
+				So(attributes["reason"], ShouldEqual, "duplicate")
+			})
+
+			Convey("should perform collection actions without an id", func() {
+				doc, resp, err := MethodAction("POST", baseURL, "tests", "", "import", nil)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusAccepted)
+				So(doc.Meta, ShouldResemble, map[string]interface{}{"job": "1"})
+			})
+		})
 	})
 }
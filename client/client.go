@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -24,6 +25,12 @@ func Document(response *http.Response, mode jsh.DocumentMode) (*jsh.Document, *j
 	}
 
 	document.Status = response.StatusCode
+
+	etag := response.Header.Get("ETag")
+	if etag != "" && document.Mode == jsh.ObjectMode && document.HasData() {
+		document.First().ETag = etag
+	}
+
 	return document, nil
 }
 
@@ -131,10 +138,20 @@ func Do(request *http.Request, mode jsh.DocumentMode) (*jsh.Document, *http.Resp
 
 /*
 ParseResponse handles parsing an HTTP response into a JSON Document if
-possible.
+possible. RFC 7807 "application/problem+json" responses are converted into a
+Document containing the equivalent jsh.ErrorList.
 */
 func ParseResponse(response *http.Response, mode jsh.DocumentMode) (*jsh.Document, error) {
 
+	if isProblem(response) {
+		document, err := ProblemDocument(response)
+		if err != nil {
+			return nil, err
+		}
+
+		return document, nil
+	}
+
 	skipCodes := []int{
 		http.StatusNoContent,
 		http.StatusNotFound,
@@ -154,6 +171,50 @@ func ParseResponse(response *http.Response, mode jsh.DocumentMode) (*jsh.Documen
 	return document, nil
 }
 
+/*
+ProblemDocument parses an RFC 7807 problem details response body into a Document
+in ErrorMode before closing it. If the problem doesn't specify a status, the
+response's status code is used.
+*/
+func ProblemDocument(response *http.Response) (*jsh.Document, *jsh.Error) {
+	defer response.Body.Close()
+
+	problem := &jsh.Problem{}
+	decodeErr := json.NewDecoder(io.LimitReader(response.Body, jsh.MaxContentLength)).Decode(problem)
+	if decodeErr != nil {
+		return nil, jsh.ISE(fmt.Sprintf("Error parsing problem details: %s", decodeErr.Error()))
+	}
+
+	problemErr := problem.ToError()
+	if problemErr.Status == 0 {
+		problemErr.Status = response.StatusCode
+	}
+
+	document := jsh.New()
+	err := document.AddError(problemErr)
+	if err != nil {
+		return nil, err
+	}
+
+	document.Status = response.StatusCode
+
+	return document, nil
+}
+
+// isProblem checks whether the response is an RFC 7807 problem details document
+func isProblem(response *http.Response) bool {
+	if response.Header == nil {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return mediaType == jsh.ProblemContentType
+}
+
 // NewRequest builds a basic request object with the necessary configurations to
 // achieve JSON API compatibility
 func NewRequest(method string, urlStr string, body io.Reader) (*http.Request, error) {
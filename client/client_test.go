@@ -58,6 +58,20 @@ func TestParseResponse(t *testing.T) {
 			So(doc, ShouldBeNil)
 			So(err, ShouldBeNil)
 		})
+
+		Convey("should surface problem details as an ErrorList", func() {
+			response.StatusCode = http.StatusServiceUnavailable
+			response.Header = http.Header{}
+			response.Header.Set("Content-Type", "application/problem+json; charset=utf-8")
+			response.Body = jsh.CreateReadCloser([]byte(`{"title": "Maintenance", "detail": "Back soon"}`))
+
+			doc, err := ParseResponse(response, jsh.ObjectMode)
+			So(err, ShouldBeNil)
+			So(doc.HasErrors(), ShouldBeTrue)
+			So(doc.Status, ShouldEqual, http.StatusServiceUnavailable)
+			So(doc.Errors[0].Status, ShouldEqual, http.StatusServiceUnavailable)
+			So(doc.Errors[0].Title, ShouldEqual, "Maintenance")
+		})
 	})
 }
 
@@ -108,7 +122,7 @@ func TestResponseParsing(t *testing.T) {
 func testAPI() *jshapi.API {
 
 	resource := jshapi.NewMockResource("tests", 1, nil)
-	resource.Action("testAction", func(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	resource.Action("testAction", func(ctx context.Context, id string) (*jsh.Object, error) {
 		object, err := jsh.NewObject("1", "tests", []string{"testAction"})
 		if err != nil {
 			log.Fatal(err.Error())
@@ -116,6 +130,17 @@ func testAPI() *jshapi.API {
 
 		return object, nil
 	})
+	resource.MethodAction("POST", "cancel", func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error) {
+		object, err := jsh.NewObject(id, "tests", request.Meta)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		return object, nil
+	})
+	resource.CollectionAction("POST", "import", func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error) {
+		return &jsh.Document{Status: http.StatusAccepted, Meta: map[string]interface{}{"job": "1"}}, nil
+	})
 
 	api := jshapi.New("")
 	api.Add(resource)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/derekdowling/go-json-spec-handler"
 )
@@ -27,7 +28,8 @@ func Patch(baseURL string, object *jsh.Object) (*jsh.Document, *http.Response, e
 
 // PatchRequest returns a fully formatted request with JSON body for performing
 // a JSONAPI PATCH. This is useful for if you need to set custom headers on the
-// request. Otherwise just use "jsc.Patch".
+// request. Otherwise just use "jsc.Patch". If the object carries an ETag from
+// the response it was fetched with, it is sent via If-Match.
 func PatchRequest(baseURL string, object *jsh.Object) (*http.Request, error) {
 	u, err := url.Parse(baseURL)
 	if err != nil {
@@ -46,5 +48,11 @@ func PatchRequest(baseURL string, object *jsh.Object) (*http.Request, error) {
 		return nil, err
 	}
 
+	// make the update conditional on the version the object was fetched with,
+	// weak ETags can never satisfy If-Match
+	if object.ETag != "" && !strings.HasPrefix(object.ETag, "W/") {
+		request.Header.Set("If-Match", object.ETag)
+	}
+
 	return request, nil
 }
@@ -0,0 +1,231 @@
+package jsh
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Object Meta keys that jsh uses to build conditional request validators
+const (
+	// MetaVersion holds the version of a resource, see Object.Version()
+	MetaVersion = "version"
+	// MetaLastModified holds the modification time of a resource, see
+	// Object.LastModified()
+	MetaLastModified = "lastModified"
+)
+
+// ETagMode controls how entity tags are computed for responses
+type ETagMode int
+
+const (
+	// NoETag disables ETags computed over the response body. Objects that carry
+	// a version still produce an ETag.
+	NoETag ETagMode = iota
+	// WeakETag computes a weak ETag, W/"<hash>", over the response body
+	WeakETag
+	// StrongETag computes a strong ETag, "<hash>", over the response body
+	StrongETag
+)
+
+// versionHashLength is the number of content hash characters in a version ETag
+const versionHashLength = 12
+
+// versionHashSeparator separates the version from the content hash of a version
+// ETag, versions containing it never produce a version ETag
+const versionHashSeparator = ";"
+
+/*
+ETag returns the entity tag for a document. A single object with its ETag set is
+sent with that ETag. An object carrying a version produces a strong ETag of the
+form "<version>;<hash>", where the hash covers the marshaled content so that
+every representation of the version, i.e. with other includes, sparse fieldsets
+or languages, has its own tag. ETagVersion recovers the version from it.
+Otherwise a tag is computed over the marshaled content as specified by mode.
+Returns "" if no tag applies, versions that contain ";" or characters that
+aren't valid in ETags don't produce a version ETag.
+*/
+func ETag(document *Document, content []byte, mode ETagMode) string {
+	if document.Mode == ObjectMode && document.HasData() {
+		object := document.First()
+		if object.ETag != "" {
+			return object.ETag
+		}
+
+		version := object.Version()
+		if version != "" && !strings.Contains(version, versionHashSeparator) {
+			etag, valid := quoteETag(version + versionHashSeparator + hashContent(content)[:versionHashLength])
+			if valid {
+				return etag
+			}
+		}
+	}
+
+	// content hashes are hex encoded and therefore always valid
+	switch mode {
+	case WeakETag:
+		return `W/"` + hashContent(content) + `"`
+	case StrongETag:
+		return `"` + hashContent(content) + `"`
+	}
+
+	return ""
+}
+
+/*
+ETagVersion returns the resource version carried by a strong entity tag, i.e.
+both `"3;a1b2c3d4e5f6"` as built by ETag and a plain `"3"` result in `3`. As
+versions never contain ";", anything following it is the content hash. Returns
+"" for weak tags, as they never identify a version.
+*/
+func ETagVersion(etag string) string {
+	etag = strings.TrimSpace(etag)
+	if strings.HasPrefix(etag, "W/") {
+		return ""
+	}
+
+	tag := strings.Trim(etag, `"`)
+
+	separator := strings.Index(tag, versionHashSeparator)
+	if separator >= 0 {
+		return tag[:separator]
+	}
+
+	return tag
+}
+
+/*
+LastModified returns the most recent modification time of the document's data
+objects. Returns false if any of the objects doesn't carry a timestamp, as the
+modification time of the document as a whole cannot be known.
+*/
+func (d *Document) LastModified() (time.Time, bool) {
+	if !d.HasData() {
+		return time.Time{}, false
+	}
+
+	var latest time.Time
+	for _, object := range d.Data {
+		modified, exists := object.LastModified()
+		if !exists {
+			return time.Time{}, false
+		}
+
+		if modified.After(latest) {
+			latest = modified
+		}
+	}
+
+	return latest, true
+}
+
+/*
+ETagMatches checks whether an If-None-Match or If-Match header value contains the
+provided ETag. A "*" value matches any ETag. When weak is true, the weak comparison
+function is used (If-None-Match), otherwise weak ETags never match (If-Match).
+*/
+func ETagMatches(header string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		switch {
+		case candidate == "*":
+			return true
+		case weak && strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/"):
+			return true
+		case !weak && candidate == etag:
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+notModified sets the ETag and Last-Modified validators for successful responses,
+and reports whether a GET or HEAD request's If-None-Match or If-Modified-Since
+preconditions allow responding with 304 Not Modified instead. Only GET and HEAD
+responses receive ETags computed over the response body.
+*/
+func (c *Config) notModified(w http.ResponseWriter, r *http.Request, document *Document, content []byte) bool {
+	if document.HasErrors() || document.Status < 200 || document.Status >= 300 {
+		return false
+	}
+
+	conditionalGet := (r.Method == "GET" || r.Method == "HEAD") && document.Status == http.StatusOK
+
+	mode := c.ETag
+	if !conditionalGet {
+		mode = NoETag
+	}
+
+	etag := ETag(document, content, mode)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	lastModified, hasLastModified := document.LastModified()
+	if hasLastModified {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if !conditionalGet {
+		return false
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since as per RFC 7232
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch != "" {
+		return ETagMatches(ifNoneMatch, etag, true)
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	if ifModifiedSince != "" && hasLastModified {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err == nil && !lastModified.Truncate(time.Second).After(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+UnquoteETag strips the quotes from an entity tag, i.e. `"3"` becomes `3`. Weak
+tags keep their W/ prefix.
+*/
+func UnquoteETag(etag string) string {
+	etag = strings.TrimSpace(etag)
+	if strings.HasPrefix(etag, "W/") {
+		return "W/" + strings.Trim(etag[2:], `"`)
+	}
+
+	return strings.Trim(etag, `"`)
+}
+
+func hashContent(content []byte) string {
+	sum := sha1.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// quoteETag quotes an entity tag, returning false if tag contains characters
+// that aren't allowed in ETags as per RFC 9110
+func quoteETag(tag string) (string, bool) {
+	for i := 0; i < len(tag); i++ {
+		if tag[i] == '"' || tag[i] < 0x21 || tag[i] == 0x7f {
+			return "", false
+		}
+	}
+
+	return `"` + tag + `"`, true
+}
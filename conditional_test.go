@@ -0,0 +1,128 @@
+package jsh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConditional(t *testing.T) {
+
+	Convey("Conditional Request Tests", t, func() {
+
+		config := DefaultConfig()
+		config.ETag = WeakETag
+
+		request := &http.Request{Method: "GET", Header: http.Header{}}
+		writer := httptest.NewRecorder()
+
+		object := &Object{
+			ID:         "1",
+			Type:       "user",
+			Attributes: json.RawMessage(`{"foo":"bar"}`),
+		}
+
+		Convey("->ETagMatches()", func() {
+			So(ETagMatches(`"a", W/"b"`, `"b"`, true), ShouldBeTrue)
+			So(ETagMatches(`"a", W/"b"`, `W/"b"`, false), ShouldBeFalse)
+			So(ETagMatches(`*`, `"c"`, false), ShouldBeTrue)
+			So(ETagMatches(`"a"`, `"c"`, true), ShouldBeFalse)
+		})
+
+		Convey("->Send()", func() {
+
+			Convey("should include a weak ETag", func() {
+				err := config.Send(writer, request, object)
+				So(err, ShouldBeNil)
+				So(writer.Code, ShouldEqual, http.StatusOK)
+				So(writer.Header().Get("ETag"), ShouldStartWith, `W/"`)
+			})
+
+			Convey("should not include an ETag by default", func() {
+				err := Send(writer, request, object)
+				So(err, ShouldBeNil)
+				So(writer.Header().Get("ETag"), ShouldBeEmpty)
+			})
+
+			Convey("should prefer the object's version", func() {
+				object.Meta = map[string]interface{}{MetaVersion: 3}
+
+				err := Send(writer, request, object)
+				So(err, ShouldBeNil)
+				etag := writer.Header().Get("ETag")
+				So(etag, ShouldStartWith, `"3;`)
+				So(ETagVersion(etag), ShouldEqual, "3")
+
+				Convey("with a tag per representation", func() {
+					object.Meta["title"] = "other"
+					other := httptest.NewRecorder()
+
+					So(Send(other, request, object), ShouldBeNil)
+					So(other.Header().Get("ETag"), ShouldStartWith, `"3;`)
+					So(other.Header().Get("ETag"), ShouldNotEqual, etag)
+				})
+			})
+
+			Convey("should keep versions that look like content hashes", func() {
+				So(ETagVersion(`"123e4567-e89b-12d3-a456-426614174000"`), ShouldEqual, "123e4567-e89b-12d3-a456-426614174000")
+				So(ETagVersion(`"123e4567-e89b-12d3-a456-426614174000;a1b2c3d4e5f6"`), ShouldEqual, "123e4567-e89b-12d3-a456-426614174000")
+			})
+
+			Convey("should not build version ETags from versions containing the separator", func() {
+				object.Meta = map[string]interface{}{MetaVersion: "a;b"}
+
+				err := Send(writer, request, object)
+				So(err, ShouldBeNil)
+				So(writer.Header().Get("ETag"), ShouldBeEmpty)
+			})
+
+			Convey("should not build ETags from invalid versions", func() {
+				object.Meta = map[string]interface{}{MetaVersion: `a"b`}
+
+				err := Send(writer, request, object)
+				So(err, ShouldBeNil)
+				So(writer.Header().Get("ETag"), ShouldBeEmpty)
+			})
+
+			Convey("should respond 304 for a matching If-None-Match", func() {
+				So(config.Send(writer, request, object), ShouldBeNil)
+				etag := writer.Header().Get("ETag")
+
+				request.Header.Set("If-None-Match", etag)
+				notModified := httptest.NewRecorder()
+
+				err := config.Send(notModified, request, object)
+				So(err, ShouldBeNil)
+				So(notModified.Code, ShouldEqual, http.StatusNotModified)
+				So(notModified.Body.Len(), ShouldEqual, 0)
+				So(notModified.Header().Get("ETag"), ShouldEqual, etag)
+			})
+
+			Convey("should handle If-Modified-Since", func() {
+				modified := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+				object.Meta = map[string]interface{}{MetaLastModified: modified.Format(time.RFC3339)}
+
+				Convey("with an unmodified resource", func() {
+					request.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+
+					err := Send(writer, request, List{object})
+					So(err, ShouldBeNil)
+					So(writer.Code, ShouldEqual, http.StatusNotModified)
+					So(writer.Header().Get("Last-Modified"), ShouldEqual, modified.Format(http.TimeFormat))
+				})
+
+				Convey("with a modified resource", func() {
+					request.Header.Set("If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+
+					err := Send(writer, request, List{object})
+					So(err, ShouldBeNil)
+					So(writer.Code, ShouldEqual, http.StatusOK)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package jsh
+
+/*
+Config carries the settings used while building, parsing, and sending documents.
+The package level functions such as Send, SendDocument, and ParseObject use a
+DefaultConfig(), use your own Config when a single process serves multiple APIs
+that require different settings:
+
+	public := jsh.DefaultConfig()
+
+	internal := jsh.DefaultConfig()
+	internal.ExposeInternalErrors = true
+
+	internal.Send(w, r, object)
+*/
+type Config struct {
+	// IncludeJSONAPIVersion includes the `jsonapi` top-level member in responses
+	IncludeJSONAPIVersion bool
+	// DefaultErrorTitle is the Title sent for Internal Server Errors
+	DefaultErrorTitle string
+	// DefaultErrorDetail is the Detail sent for Internal Server Errors
+	DefaultErrorDetail string
+	// ExposeInternalErrors sends the internal message of an Internal Server Error
+	// as its Detail. Do NOT enable this for public facing APIs, you risk leaking
+	// sensitive information.
+	ExposeInternalErrors bool
+	// Messages is used to localize error responses, nil disables localization
+	Messages *MessageCatalog
+	// MaxContentLength is the maximum number of bytes parsed from a payload
+	MaxContentLength int64
+	// ETag controls whether GET responses include an ETag computed over the
+	// response body. Combined with If-None-Match, clients can avoid downloading
+	// unchanged documents. See ETag() for details.
+	ETag ETagMode
+}
+
+/*
+DefaultConfig returns a new Config reflecting the current package level settings:
+IncludeJSONAPIVersion, DefaultErrorTitle, DefaultErrorDetail, and Messages.
+Modifying the returned Config does not affect the package level settings.
+*/
+func DefaultConfig() *Config {
+	return &Config{
+		IncludeJSONAPIVersion: IncludeJSONAPIVersion,
+		DefaultErrorTitle:     DefaultErrorTitle,
+		DefaultErrorDetail:    DefaultErrorDetail,
+		Messages:              Messages,
+		MaxContentLength:      MaxContentLength,
+	}
+}
+
+/*
+prepareErrors applies the configured Internal Server Error presentation and
+localization to a list of errors that is about to be sent. Returns the language
+the errors were localized into, or "" if they weren't.
+*/
+func (c *Config) prepareErrors(acceptLanguage string, errors ErrorList) (ErrorList, string) {
+	prepared := make(ErrorList, len(errors))
+
+	for i, err := range errors {
+		if err.Code != CodeInternalServerError {
+			prepared[i] = err
+			continue
+		}
+
+		// only replace the package level defaults, leave customized errors alone
+		ise := *err
+		if ise.Title == DefaultErrorTitle && c.DefaultErrorTitle != "" {
+			ise.Title = c.DefaultErrorTitle
+		}
+		if ise.Detail == DefaultErrorDetail && c.DefaultErrorDetail != "" {
+			ise.Detail = c.DefaultErrorDetail
+		}
+
+		prepared[i] = &ise
+	}
+
+	var language string
+	if c.Messages != nil {
+		negotiated := c.Messages.Negotiate(acceptLanguage)
+		if negotiated != c.Messages.DefaultLanguage {
+			var applied bool
+			prepared, applied = c.Messages.localizeList(negotiated, prepared)
+			if applied {
+				language = negotiated
+			}
+		}
+	}
+
+	if c.ExposeInternalErrors {
+		for i, err := range prepared {
+			if err.Code == CodeInternalServerError && err.ISE != "" {
+				exposed := *err
+				exposed.Detail = err.ISE
+				prepared[i] = &exposed
+			}
+		}
+	}
+
+	return prepared, language
+}
+
+// errorTitle is used for plain text responses when something goes horribly wrong
+func (c *Config) errorTitle() string {
+	if c.DefaultErrorTitle != "" {
+		return c.DefaultErrorTitle
+	}
+
+	return DefaultErrorTitle
+}
+
+// maxContentLength falls back to MaxContentLength if not configured
+func (c *Config) maxContentLength() int64 {
+	if c.MaxContentLength > 0 {
+		return c.MaxContentLength
+	}
+
+	return MaxContentLength
+}
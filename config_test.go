@@ -0,0 +1,62 @@
+package jsh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfig(t *testing.T) {
+
+	Convey("Config Tests", t, func() {
+
+		request := &http.Request{Method: "GET"}
+		writer := httptest.NewRecorder()
+
+		config := DefaultConfig()
+
+		Convey("->DefaultConfig()", func() {
+			So(config.IncludeJSONAPIVersion, ShouldEqual, IncludeJSONAPIVersion)
+			So(config.DefaultErrorTitle, ShouldEqual, DefaultErrorTitle)
+			So(config.Messages, ShouldEqual, Messages)
+		})
+
+		Convey("->New()", func() {
+			config.IncludeJSONAPIVersion = false
+			So(config.New().JSONAPI, ShouldBeNil)
+			So(New().JSONAPI, ShouldNotBeNil)
+		})
+
+		Convey("->Send()", func() {
+			sentErrors := func() ErrorList {
+				doc := &struct {
+					Errors ErrorList `json:"errors"`
+				}{}
+				So(json.Unmarshal(writer.Body.Bytes(), doc), ShouldBeNil)
+				return doc.Errors
+			}
+
+			Convey("should use the configured ISE title and detail", func() {
+				config.DefaultErrorTitle = "Oops"
+				config.DefaultErrorDetail = "Try again later"
+
+				err := config.Send(writer, request, ISE("disk full"))
+				So(err, ShouldBeNil)
+				So(writer.Code, ShouldEqual, http.StatusInternalServerError)
+				So(sentErrors()[0].Title, ShouldEqual, "Oops")
+				So(sentErrors()[0].Detail, ShouldEqual, "Try again later")
+			})
+
+			Convey("should expose internal errors when configured to", func() {
+				config.ExposeInternalErrors = true
+
+				err := config.Send(writer, request, ISE("disk full"))
+				So(err, ShouldBeNil)
+				So(sentErrors()[0].Detail, ShouldEqual, "disk full")
+			})
+		})
+	})
+}
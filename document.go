@@ -18,6 +18,9 @@ const (
 	ListMode
 	// ErrorMode enforces error response specifications
 	ErrorMode
+	// LinkageMode enforces resource linkage request/response specifications, as
+	// used by relationship URLs. The linkage is held by Document.Linkage.
+	LinkageMode
 )
 
 // IncludeJSONAPIVersion is an option that allows consumers to include/remove the `jsonapi`
@@ -47,6 +50,8 @@ type Document struct {
 	Status int `json:"-"`
 	// DataMode to enforce for the document
 	Mode DocumentMode `json:"-"`
+	// Linkage is the resource linkage sent in place of Data in LinkageMode
+	Linkage *Relationship `json:"-"`
 	// empty is used to signify that the response shouldn't contain a json payload
 	// in the case that we only want to return an HTTP Status Code in order to bypass
 	// validation steps.
@@ -60,8 +65,13 @@ type Document struct {
 New instantiates a new JSON Document object.
 */
 func New() *Document {
+	return DefaultConfig().New()
+}
+
+// New instantiates a new JSON Document object using the Config's settings
+func (c *Config) New() *Document {
 	json := &Document{}
-	if IncludeJSONAPIVersion {
+	if c.IncludeJSONAPIVersion {
 		json.JSONAPI = &JSONAPI{
 			Version: JSONAPIVersion,
 		}
@@ -73,10 +83,21 @@ func New() *Document {
 /*
 Build creates a Sendable Document with the provided sendable payload, either Data or
 errors. Build also assumes you've already validated your data with .Validate() so
-it should be used carefully.
+it should be used carefully. A *Document payload is returned as is.
 */
 func Build(payload Sendable) *Document {
-	document := New()
+	return DefaultConfig().Build(payload)
+}
+
+// Build works like the package level Build, using the Config's settings
+func (c *Config) Build(payload Sendable) *Document {
+	// documents that were prepared by hand are sent as they are
+	prepared, isDocument := payload.(*Document)
+	if isDocument {
+		return prepared
+	}
+
+	document := c.New()
 	document.validated = true
 
 	object, isObject := payload.(*Object)
@@ -100,6 +121,18 @@ func Build(payload Sendable) *Document {
 		document.Mode = ErrorMode
 	}
 
+	relationship, isRelationship := payload.(*Relationship)
+	if isRelationship {
+		document.Linkage = relationship
+		document.Links = relationship.Links
+		document.Status = http.StatusOK
+		document.Mode = LinkageMode
+
+		if len(relationship.Meta) > 0 {
+			document.Meta = relationship.Meta
+		}
+	}
+
 	errorList, isErrorList := payload.(ErrorList)
 	if isErrorList {
 		document.Errors = errorList
@@ -148,6 +181,19 @@ func (d *Document) Validate(r *http.Request, isResponse bool) *Error {
 		if !d.HasErrors() && d.Data == nil {
 			return ISE("Data cannot be nil in 'ListMode', use empty array")
 		}
+	case LinkageMode:
+		if d.Linkage == nil {
+			return ISE("Linkage must be set in 'LinkageMode'")
+		}
+
+		if d.HasData() {
+			return ISE("Cannot set data objects in 'LinkageMode', use Linkage")
+		}
+
+		err := d.Linkage.Validate(r, isResponse)
+		if err != nil {
+			return err
+		}
 	}
 
 	if !d.HasData() && d.Included != nil {
@@ -299,6 +345,24 @@ func (d *Document) MarshalJSON() ([]byte, error) {
 
 	case ListMode:
 		return json.Marshal(doc)
+
+	case LinkageMode:
+		// subtype that overrides the data List with the document's resource linkage
+		type MarshalLinkage struct {
+			MarshalDoc
+			Data interface{} `json:"data"`
+		}
+
+		var data interface{} = ResourceLinkage{}
+		if d.Linkage != nil {
+			data = d.Linkage.linkageData()
+		}
+
+		return json.Marshal(MarshalLinkage{
+			MarshalDoc: doc,
+			Data:       data,
+		})
+
 	default:
 		return nil, ISE(fmt.Sprintf("Unexpected DocumentMode value when marshaling: %d", d.Mode))
 	}
@@ -118,6 +118,20 @@ func TestDocument(t *testing.T) {
 				So(doc.Status, ShouldEqual, err.Status)
 				So(doc.Mode, ShouldEqual, ErrorMode)
 			})
+
+			Convey("should accept a relationship", func() {
+				relationship := &Relationship{
+					Links: &Links{Self: NewLink("/articles/1/relationships/author")},
+					Data:  ResourceLinkage{&ResourceIdentifier{Type: "people", ID: "9"}},
+					ToOne: true,
+				}
+				doc := Build(relationship)
+
+				So(doc.Linkage, ShouldEqual, relationship)
+				So(doc.Links, ShouldEqual, relationship.Links)
+				So(doc.Status, ShouldEqual, http.StatusOK)
+				So(doc.Mode, ShouldEqual, LinkageMode)
+			})
 		})
 
 		Convey("->Validate()", func() {
@@ -210,6 +224,37 @@ func TestDocumentMarshaling(t *testing.T) {
 				})
 			})
 
+			Convey("LinkageMode", func() {
+
+				marshalData := func(relationship *Relationship) string {
+					rawJSON, err := json.Marshal(Build(relationship))
+					So(err, ShouldBeNil)
+
+					m := map[string]json.RawMessage{}
+					err = json.Unmarshal(rawJSON, &m)
+					So(err, ShouldBeNil)
+
+					return string(m["data"])
+				}
+
+				identifier := &ResourceIdentifier{Type: "people", ID: "9"}
+
+				Convey("should marshal to-one linkage as an object", func() {
+					data := marshalData(&Relationship{Data: ResourceLinkage{identifier}, ToOne: true})
+					So(data, ShouldEqual, `{"type":"people","id":"9"}`)
+				})
+
+				Convey("should marshal empty to-one linkage as null", func() {
+					data := marshalData(&Relationship{ToOne: true})
+					So(data, ShouldEqual, "null")
+				})
+
+				Convey("should marshal to-many linkage as an array", func() {
+					So(marshalData(&Relationship{Data: ResourceLinkage{identifier}}), ShouldEqual, `[{"type":"people","id":"9"}]`)
+					So(marshalData(&Relationship{}), ShouldEqual, "[]")
+				})
+			})
+
 			Convey("ObjectMode", func() {
 
 				doc := New()
@@ -16,6 +16,23 @@ var DefaultErrorDetail = "Request failed, something went wrong."
 // DefaultTitle can be customized to provide a more customized ISE Title
 var DefaultErrorTitle = "Internal Server Error"
 
+// Error codes used by the errors that jsh builds. Codes are used to look up
+// localized messages, see MessageCatalog.
+const (
+	CodeInternalServerError  = "internal_server_error"
+	CodeInvalidAttribute     = "invalid_attribute"
+	CodeSpecificationError   = "specification_error"
+	CodeNotFound             = "not_found"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeRouteNotFound        = "route_not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeConflict             = "conflict"
+	CodeForbidden            = "forbidden"
+	CodeInvalidRelationship  = "invalid_relationship"
+)
+
 /*
 ErrorType represents the common interface requirements that libraries may
 specify if they would like to accept either a single error or a list.
@@ -83,13 +100,27 @@ http://jsonapi.org/format/#error-objects
 	jsh.Send(w, r, error)
 */
 type Error struct {
+	// ID is a unique identifier for this particular occurrence of the problem
+	ID    string           `json:"id,omitempty"`
+	Links map[string]*Link `json:"links,omitempty"`
+	// Code is an application specific error code, also used to look up
+	// localized messages
+	Code   string `json:"code,omitempty"`
 	Title  string `json:"title"`
 	Detail string `json:"detail"`
 	Status int    `json:"status,string"`
 	Source struct {
 		Pointer string `json:"pointer"`
+		// Parameter is the query parameter that caused the error
+		Parameter string `json:"parameter,omitempty"`
 	} `json:"source"`
-	ISE string `json:"-"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+	// Params are substituted into localized Detail messages, see MessageCatalog
+	Params map[string]string `json:"-"`
+	ISE    string            `json:"-"`
+	// Err is the original Go error this Error was mapped from, if any. Useful
+	// for logging, it is never sent to the client.
+	Err error `json:"-"`
 }
 
 /*
@@ -110,6 +141,14 @@ func (e *Error) Error() string {
 	return msg
 }
 
+/*
+Unwrap returns the original Go error, allowing errors.Is and errors.As to see
+through a mapped Error.
+*/
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 /*
 Validate ensures that the an error meets all JSON API criteria.
 */
@@ -141,6 +180,7 @@ can gracefully log ISE's internally before sending them.
 */
 func ISE(internalMessage string) *Error {
 	return &Error{
+		Code:   CodeInternalServerError,
 		Title:  DefaultErrorTitle,
 		Detail: DefaultErrorDetail,
 		Status: http.StatusInternalServerError,
@@ -155,9 +195,11 @@ user safe message. The parameter "attribute" will format err.Source.Pointer to b
 */
 func InputError(msg string, attribute string) *Error {
 	err := &Error{
+		Code:   CodeInvalidAttribute,
 		Title:  "Invalid Attribute",
 		Detail: msg,
 		Status: 422,
+		Params: map[string]string{"attribute": attribute},
 	}
 
 	// Assign this after the fact, easier to do
@@ -166,9 +208,76 @@ func InputError(msg string, attribute string) *Error {
 	return err
 }
 
+/*
+RelationshipError creates a HTTP Status 422 error for an invalid relationship of
+a request object, err.Source.Pointer is set to "/data/relationships/<relationship>".
+*/
+func RelationshipError(msg string, relationship string) *Error {
+	err := &Error{
+		Code:   CodeInvalidRelationship,
+		Title:  "Invalid Relationship",
+		Detail: msg,
+		Status: 422,
+		Params: map[string]string{"relationship": relationship},
+	}
+	err.Source.Pointer = fmt.Sprintf("/data/relationships/%s", relationship)
+
+	return err
+}
+
+/*
+ParameterError creates a 400 error for an invalid query parameter, such as an
+unsupported "include" path. The parameter is set as err.Source.Parameter.
+*/
+func ParameterError(msg string, parameter string) *Error {
+	err := &Error{
+		Code:   CodeInvalidParameter,
+		Title:  "Invalid Query Parameter",
+		Detail: msg,
+		Status: http.StatusBadRequest,
+		Params: map[string]string{"parameter": parameter},
+	}
+	err.Source.Parameter = parameter
+
+	return err
+}
+
+// Forbidden returns a 403 formatted error, used when the client isn't allowed to
+// perform the request
+func Forbidden(detail string) *Error {
+	return &Error{
+		Code:   CodeForbidden,
+		Title:  "Forbidden",
+		Detail: detail,
+		Status: http.StatusForbidden,
+	}
+}
+
+/*
+Conflict creates a 409 error, i.e. for an attribute value that must be unique.
+The parameter "attribute" will format err.Source.Pointer to be
+"/data/attributes/<attribute>", it may be left empty.
+*/
+func Conflict(msg string, attribute string) *Error {
+	err := &Error{
+		Code:   CodeConflict,
+		Title:  "Conflict",
+		Detail: msg,
+		Status: http.StatusConflict,
+		Params: map[string]string{"attribute": attribute},
+	}
+
+	if attribute != "" {
+		err.Source.Pointer = fmt.Sprintf("/data/attributes/%s", strings.ToLower(attribute))
+	}
+
+	return err
+}
+
 // SpecificationError is used whenever the Client violates the JSON API Spec
 func SpecificationError(detail string) *Error {
 	return &Error{
+		Code:   CodeSpecificationError,
 		Title:  "JSON API Specification Error",
 		Detail: detail,
 		Status: http.StatusNotAcceptable,
@@ -178,8 +287,61 @@ func SpecificationError(detail string) *Error {
 // NotFound returns a 404 formatted error
 func NotFound(resourceType string, id string) *Error {
 	return &Error{
+		Code:   CodeNotFound,
 		Title:  "Not Found",
 		Detail: fmt.Sprintf("No resource of type '%s' exists for ID: %s", resourceType, id),
 		Status: http.StatusNotFound,
+		Params: map[string]string{"type": resourceType, "id": id},
+	}
+}
+
+// RouteNotFound returns a 404 formatted error for a path that no route handles
+func RouteNotFound(path string) *Error {
+	return &Error{
+		Code:   CodeRouteNotFound,
+		Title:  "Not Found",
+		Detail: fmt.Sprintf("No route exists for path: %s", path),
+		Status: http.StatusNotFound,
+		Params: map[string]string{"path": path},
+	}
+}
+
+/*
+MethodNotAllowed returns a 405 formatted error. When sending it, make sure to
+set the Allow header listing the supported methods.
+*/
+func MethodNotAllowed(method string) *Error {
+	return &Error{
+		Code:   CodeMethodNotAllowed,
+		Title:  "Method Not Allowed",
+		Detail: fmt.Sprintf("Method %s is not supported by this route", method),
+		Status: http.StatusMethodNotAllowed,
+		Params: map[string]string{"method": method},
+	}
+}
+
+/*
+PreconditionFailed returns a 412 formatted error, used when a conditional request
+such as one carrying If-Match no longer matches the current state of a resource.
+*/
+func PreconditionFailed(detail string) *Error {
+	return &Error{
+		Code:   CodePreconditionFailed,
+		Title:  "Precondition Failed",
+		Detail: detail,
+		Status: http.StatusPreconditionFailed,
+	}
+}
+
+/*
+PreconditionRequired returns a 428 formatted error, used when a request must be
+made conditional, i.e. by providing an If-Match header.
+*/
+func PreconditionRequired(detail string) *Error {
+	return &Error{
+		Code:   CodePreconditionRequired,
+		Title:  "Precondition Required",
+		Detail: detail,
+		Status: http.StatusPreconditionRequired,
 	}
 }
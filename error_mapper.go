@@ -0,0 +1,167 @@
+package jsh
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/*
+ErrorMapper converts a Go error into a JSON API compatible ErrorType. A mapper
+should return nil if it doesn't know how to handle the provided error so that
+the next registered mapper can be consulted.
+*/
+type ErrorMapper func(err error) ErrorType
+
+var (
+	errorMappersLock sync.RWMutex
+	errorMappers     []ErrorMapper
+)
+
+/*
+RegisterErrorMapper adds a mapper to the registry used by MapError. Mappers are
+consulted in the order in which they were registered, the first one to return
+a non-nil ErrorType wins. This allows your domain layer to return plain Go errors
+while still producing proper JSON API error responses:
+
+	jsh.RegisterErrorMapper(jsh.MapSentinel(sql.ErrNoRows, func(err error) ErrorType {
+		return &jsh.Error{Title: "Not Found", Detail: "Resource not found", Status: 404}
+	}))
+
+	jsh.RegisterErrorMapper(jsh.MapAs(&ValidationError{}, func(err error) ErrorType {
+		validationErr := err.(*ValidationError)
+		return jsh.InputError(validationErr.Message, validationErr.Field)
+	}))
+*/
+func RegisterErrorMapper(mapper ErrorMapper) {
+	errorMappersLock.Lock()
+	defer errorMappersLock.Unlock()
+
+	errorMappers = append(errorMappers, mapper)
+}
+
+/*
+MapSentinel builds an ErrorMapper that matches any error which wraps the sentinel
+error as determined by errors.Is.
+*/
+func MapSentinel(sentinel error, build func(err error) ErrorType) ErrorMapper {
+	return func(err error) ErrorType {
+		if !errors.Is(err, sentinel) {
+			return nil
+		}
+
+		return build(err)
+	}
+}
+
+/*
+MapAs builds an ErrorMapper that matches any error which can be converted into
+the type of target as determined by errors.As. Target is an example value of the
+error type to match, such as &ValidationError{}, or a nil pointer to an interface
+type, such as (*TemporaryError)(nil). The matched error value is passed to build.
+*/
+func MapAs(target interface{}, build func(err error) ErrorType) ErrorMapper {
+	errorInterface := reflect.TypeOf((*error)(nil)).Elem()
+
+	matchType := reflect.TypeOf(target)
+	if matchType == nil {
+		panic("jsh: MapAs target cannot be nil")
+	}
+
+	if matchType.Kind() == reflect.Ptr && matchType.Elem().Kind() == reflect.Interface {
+		matchType = matchType.Elem()
+	} else if !matchType.Implements(errorInterface) {
+		panic(fmt.Sprintf("jsh: MapAs target type %s does not implement error", matchType))
+	}
+
+	return func(err error) ErrorType {
+		// allocate a fresh target per call, mappers can be called concurrently
+		match := reflect.New(matchType)
+		if !errors.As(err, match.Interface()) {
+			return nil
+		}
+
+		matchedErr, isErr := match.Elem().Interface().(error)
+		if !isErr {
+			matchedErr = err
+		}
+
+		return build(matchedErr)
+	}
+}
+
+/*
+MapError converts any Go error into an ErrorType that can be sent as a response.
+Errors that are, or wrap, an ErrorType are returned untouched, otherwise each
+registered ErrorMapper is consulted. If no mapper handles the error, an ISE is
+returned. In all mapped cases, the original error is preserved via Error.Err so
+that it can be logged.
+
+A nil error, or an error interface holding a nil pointer (a common pitfall when
+returning a typed *jsh.Error), results in nil.
+*/
+func MapError(err error) ErrorType {
+	if isNilError(err) {
+		return nil
+	}
+
+	// errors wrapping an ErrorType, i.e. via fmt.Errorf("...: %w", err), are sent
+	// as the wrapped ErrorType
+	var errType ErrorType
+	if errors.As(err, &errType) && !isNilError(errType) {
+		return errType
+	}
+
+	errorMappersLock.RLock()
+	mappers := errorMappers
+	errorMappersLock.RUnlock()
+
+	for _, mapper := range mappers {
+		mapped := mapper(err)
+		if isNilError(mapped) {
+			continue
+		}
+
+		preserveError(mapped, err)
+		return mapped
+	}
+
+	ise := ISE(fmt.Sprintf("Unmapped error: %s", err.Error()))
+	ise.Err = err
+
+	return ise
+}
+
+// preserveError attaches the original error to any mapped errors that don't
+// already have one set
+func preserveError(mapped ErrorType, original error) {
+	switch mappedErr := mapped.(type) {
+	case *Error:
+		if mappedErr.Err == nil {
+			mappedErr.Err = original
+		}
+	case ErrorList:
+		for _, listErr := range mappedErr {
+			if listErr.Err == nil {
+				listErr.Err = original
+			}
+		}
+	}
+}
+
+// isNilError checks for both a nil interface and an interface wrapping a nil
+// pointer
+func isNilError(err interface{}) bool {
+	if err == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(err)
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func:
+		return value.IsNil()
+	}
+
+	return false
+}
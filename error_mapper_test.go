@@ -0,0 +1,93 @@
+package jsh
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errTestSentinel = errors.New("sentinel")
+
+type testValidationError struct {
+	Field string
+}
+
+func (e *testValidationError) Error() string {
+	return fmt.Sprintf("invalid field %s", e.Field)
+}
+
+func TestErrorMapper(t *testing.T) {
+
+	Convey("Error Mapper Tests", t, func() {
+
+		errorMappers = nil
+		Reset(func() {
+			errorMappers = nil
+		})
+
+		Convey("->MapError()", func() {
+
+			Convey("should return nil for nil errors", func() {
+				So(MapError(nil), ShouldBeNil)
+
+				var typedNil *Error
+				So(MapError(typedNil), ShouldBeNil)
+			})
+
+			Convey("should pass through existing ErrorTypes", func() {
+				notFound := NotFound("user", "1")
+				So(MapError(notFound), ShouldEqual, notFound)
+			})
+
+			Convey("should pass through wrapped ErrorTypes", func() {
+				notFound := NotFound("user", "1")
+				So(MapError(fmt.Errorf("load: %w", notFound)), ShouldEqual, notFound)
+			})
+
+			Convey("should fall back to an ISE that preserves the original error", func() {
+				original := errors.New("connection refused")
+				mapped := MapError(original)
+
+				So(mapped.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+				So(errors.Is(mapped, original), ShouldBeTrue)
+			})
+
+			Convey("should use a registered sentinel mapper", func() {
+				RegisterErrorMapper(MapSentinel(errTestSentinel, func(err error) ErrorType {
+					return NotFound("user", "1")
+				}))
+
+				mapped := MapError(fmt.Errorf("lookup failed: %w", errTestSentinel))
+				So(mapped.StatusCode(), ShouldEqual, http.StatusNotFound)
+				So(errors.Is(mapped, errTestSentinel), ShouldBeTrue)
+			})
+
+			Convey("should use a registered errors.As mapper", func() {
+				RegisterErrorMapper(MapAs(&testValidationError{}, func(err error) ErrorType {
+					validationErr := err.(*testValidationError)
+					return InputError(validationErr.Error(), validationErr.Field)
+				}))
+
+				mapped := MapError(fmt.Errorf("save: %w", &testValidationError{Field: "name"}))
+				So(mapped.StatusCode(), ShouldEqual, 422)
+
+				mappedErr := mapped.(*Error)
+				So(mappedErr.Source.Pointer, ShouldEqual, "/data/attributes/name")
+			})
+
+			Convey("should consult mappers in registration order", func() {
+				RegisterErrorMapper(MapSentinel(errTestSentinel, func(err error) ErrorType {
+					return SpecificationError("first")
+				}))
+				RegisterErrorMapper(MapSentinel(errTestSentinel, func(err error) ErrorType {
+					return NotFound("user", "1")
+				}))
+
+				So(MapError(errTestSentinel).StatusCode(), ShouldEqual, http.StatusNotAcceptable)
+			})
+		})
+	})
+}
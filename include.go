@@ -0,0 +1,74 @@
+package jsh
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// IncludeParam is the query parameter used to request related resources
+const IncludeParam = "include"
+
+/*
+Include is the tree of relationship paths requested via the "include" query
+parameter. For example "author,comments.author" results in:
+
+	Include{
+		"author":   Include{},
+		"comments": Include{"author": Include{}},
+	}
+*/
+type Include map[string]Include
+
+/*
+ParseInclude parses the "include" query parameter of a request. Returns an empty
+Include if the parameter isn't set, and a 400 error if it is malformed.
+*/
+func ParseInclude(r *http.Request) (Include, *Error) {
+	return NewInclude(r.URL.Query().Get(IncludeParam))
+}
+
+// NewInclude builds an Include tree from a comma separated list of dot separated
+// relationship paths
+func NewInclude(value string) (Include, *Error) {
+	include := Include{}
+	if value == "" {
+		return include, nil
+	}
+
+	for _, path := range strings.Split(value, ",") {
+		node := include
+
+		for _, relationship := range strings.Split(path, ".") {
+			if relationship == "" {
+				return nil, ParameterError(
+					fmt.Sprintf("Invalid include path '%s'", path),
+					IncludeParam,
+				)
+			}
+
+			child, exists := node[relationship]
+			if !exists {
+				child = Include{}
+				node[relationship] = child
+			}
+
+			node = child
+		}
+	}
+
+	return include, nil
+}
+
+// Relationships returns the names of the relationships included at this level of
+// the tree in sorted order
+func (i Include) Relationships() []string {
+	names := make([]string, 0, len(i))
+	for name := range i {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
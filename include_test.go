@@ -0,0 +1,43 @@
+package jsh
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInclude(t *testing.T) {
+
+	Convey("Include Tests", t, func() {
+
+		Convey("->NewInclude()", func() {
+
+			Convey("should build a tree of relationship paths", func() {
+				include, err := NewInclude("author,comments.author,comments.post")
+				So(err, ShouldBeNil)
+				So(include, ShouldResemble, Include{
+					"author":   Include{},
+					"comments": Include{"author": Include{}, "post": Include{}},
+				})
+				So(include.Relationships(), ShouldResemble, []string{"author", "comments"})
+			})
+
+			Convey("should reject empty path segments", func() {
+				_, err := NewInclude("comments..author")
+				So(err, ShouldNotBeNil)
+				So(err.Status, ShouldEqual, http.StatusBadRequest)
+				So(err.Source.Parameter, ShouldEqual, IncludeParam)
+			})
+		})
+
+		Convey("->ParseInclude()", func() {
+			request, reqErr := http.NewRequest("GET", "/articles?include=author", nil)
+			So(reqErr, ShouldBeNil)
+
+			include, err := ParseInclude(request)
+			So(err, ShouldBeNil)
+			So(include, ShouldContainKey, "author")
+		})
+	})
+}
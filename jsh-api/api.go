@@ -1,15 +1,20 @@
 package jshapi
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strings"
 
 	"goji.io"
+	"goji.io/middleware"
 	"goji.io/pat"
 
+	"github.com/derekdowling/go-json-spec-handler"
 	gojilogger "github.com/derekdowling/go-json-spec-handler/goji2-logger"
 	"github.com/derekdowling/go-stdlogger"
 )
@@ -20,14 +25,29 @@ type API struct {
 	prefix    string
 	Resources map[string]*Resource
 	Debug     bool
+	// Config used to parse requests and send responses for this API, uses
+	// jsh.DefaultConfig() if nil
+	Config *jsh.Config
+	// Sender sends and logs responses for this API, uses SendHandler if nil
+	Sender Sender
+	// Title and Version describe the API in its OpenAPI document
+	Title   string
+	Version string
+	// routes served by the API itself rather than by one of its resources
+	routes []Route
 }
 
 /*
 SendHandler allows the customization of how API responses are sent and logged. This
-is used by all jshapi.Resource objects.
+is used by all jshapi.Resource objects served by an API without its own Sender.
 */
 var SendHandler = DefaultSender(log.New(os.Stderr, "jshapi: ", log.LstdFlags))
 
+type contextKey int
+
+// apiKey is the request context key the serving API is stored under
+const apiKey contextKey = iota
+
 /*
 New initializes a new top level API Resource without doing any additional setup.
 */
@@ -39,11 +59,19 @@ func New(prefix string) *API {
 	}
 
 	// create our new API
-	return &API{
+	api := &API{
 		Mux:       goji.NewMux(),
 		prefix:    prefix,
 		Resources: map[string]*Resource{},
 	}
+
+	// make the API available to resources so they use its settings
+	api.Use(api.contextMiddleware)
+
+	// respond with a JSON API error to paths that no resource handles
+	api.Use(api.notFoundMiddleware)
+
+	return api
 }
 
 /*
@@ -65,12 +93,16 @@ The most basic implementation is:
 func Default(prefix string, debug bool, logger std.Logger) *API {
 
 	api := New(prefix)
-	SendHandler = DefaultSender(logger)
+	api.Debug = debug
+	api.Sender = DefaultSender(logger)
 
 	// register logger middleware
 	gojilogger := gojilogger.New(logger, debug)
 	api.Use(gojilogger.Middleware)
 
+	// recover from panics within the logger so that the 500 is logged
+	api.Use(Recoverer(logger))
+
 	return api
 }
 
@@ -96,13 +128,93 @@ func (a *API) Add(resource *Resource) {
 
 // RouteTree prints out all accepted routes for the API that use jshapi implemented
 // ways of adding routes through resources: NewCRUDResource(), .Get(), .Post, .Delete(),
-// .Patch(), .List(), and .NewAction()
+// .Patch(), .List(), and .NewAction(), preceded by ServeHome() and ServeOpenAPI()
 func (a *API) RouteTree() string {
 	var routes string
 
-	for _, resource := range a.Resources {
-		routes = strings.Join([]string{routes, resource.RouteTree()}, "")
+	for _, route := range a.routes {
+		routes = strings.Join([]string{routes, fmt.Sprintf("%s - %s", route.Method, route.Pattern)}, "\n")
+	}
+
+	for _, resourceType := range a.resourceTypes() {
+		routes = strings.Join([]string{routes, a.Resources[resourceType].RouteTree()}, "")
 	}
 
 	return routes
 }
+
+// resourceTypes returns the types of the API's resources in sorted order
+func (a *API) resourceTypes() []string {
+	types := make([]string, 0, len(a.Resources))
+	for resourceType := range a.Resources {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// contextMiddleware stores the API in the request context
+func (a *API) contextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), apiKey, a)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+/*
+notFoundMiddleware dispatches requests for paths that no resource handles to
+notFoundHandler. Requests for a resource's path but not one of its routes are
+answered by the resource itself with either a 404 or a 405.
+*/
+func (a *API) notFoundMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.Handler(r.Context()) == nil {
+			ctx := middleware.SetHandler(r.Context(), http.HandlerFunc(a.notFoundHandler))
+			r = r.WithContext(ctx)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// notFoundHandler responds with a JSON API formatted 404
+func (a *API) notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	a.sender()(w, r, jsh.RouteNotFound(r.URL.Path))
+}
+
+// apiFromRequest returns the API serving the request, or nil if the request
+// isn't being served by an API
+func apiFromRequest(r *http.Request) *API {
+	api, _ := r.Context().Value(apiKey).(*API)
+	return api
+}
+
+// resourcePath builds the path of a resource type served by the API, followed by
+// any additional path segments
+func (a *API) resourcePath(resourceType string, segments ...string) string {
+	prefix := "/"
+	if a != nil {
+		prefix = a.prefix
+	}
+
+	return path.Join(append([]string{prefix, resourceType}, segments...)...)
+}
+
+// config returns the API's Config, or a default one if not set
+func (a *API) config() *jsh.Config {
+	if a == nil || a.Config == nil {
+		return jsh.DefaultConfig()
+	}
+
+	return a.Config
+}
+
+// sender returns the API's Sender, or the SendHandler if not set
+func (a *API) sender() Sender {
+	if a == nil || a.Sender == nil {
+		return SendHandler
+	}
+
+	return a.Sender
+}
@@ -1,6 +1,11 @@
 package jshapi
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -48,6 +53,212 @@ func TestAPI(t *testing.T) {
 				So(resp.StatusCode, ShouldEqual, http.StatusOK)
 				So(patchErr, ShouldBeNil)
 			})
+
+			Convey("should reject If-Match for storage without versions", func() {
+				patchObj, err := jsh.NewObject("1", testResourceType, testAttrs)
+				So(err, ShouldBeNil)
+				patchObj.ETag = `"1"`
+
+				doc, resp, patchErr := jsc.Patch(baseURL, patchObj)
+				So(patchErr, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusPreconditionFailed)
+				So(doc.Errors[0].Code, ShouldEqual, jsh.CodePreconditionFailed)
+			})
+
+			Convey("should respond to unknown paths with a JSON API 404", func() {
+				resp, err := http.Get(server.URL + "/unknown")
+				So(err, ShouldBeNil)
+
+				doc, parseErr := jsc.Document(resp, jsh.ObjectMode)
+				So(parseErr, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(doc.Errors[0].Code, ShouldEqual, jsh.CodeRouteNotFound)
+			})
+
+			Convey("should respond to unknown resource routes with a JSON API 404", func() {
+				_, resp, err := jsc.Action(baseURL, testResourceType, "1", "unknown")
+
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+				So(resp.Header.Get("Content-Type"), ShouldEqual, jsh.ContentType)
+			})
+
+			Convey("should respond to unsupported methods with a JSON API 405", func() {
+				request, err := http.NewRequest("PUT", baseURL+"/"+testResourceType+"/1", nil)
+				So(err, ShouldBeNil)
+
+				doc, resp, err := jsc.Do(request, jsh.ObjectMode)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusMethodNotAllowed)
+				So(resp.Header.Get("Allow"), ShouldEqual, "GET, HEAD, PATCH, DELETE, OPTIONS")
+				So(doc.Errors[0].Code, ShouldEqual, jsh.CodeMethodNotAllowed)
+			})
+		})
+	})
+}
+
+func TestAPIConfig(t *testing.T) {
+
+	Convey("API Config Tests", t, func() {
+
+		failing := func(ctx context.Context, id string) (*jsh.Object, error) {
+			return nil, errors.New("database unavailable")
+		}
+
+		publicResource := NewResource(testResourceType)
+		publicResource.Get(failing)
+
+		public := New("public")
+		public.Add(publicResource)
+
+		internalResource := NewResource(testResourceType)
+		internalResource.Get(failing)
+
+		internal := New("internal")
+		internal.Config = jsh.DefaultConfig()
+		internal.Config.ExposeInternalErrors = true
+		internal.Config.IncludeJSONAPIVersion = false
+		internal.Add(internalResource)
+
+		sent := 0
+		internal.Sender = func(w http.ResponseWriter, r *http.Request, sendable jsh.Sendable) {
+			sent++
+			DefaultSender(log.New(ioutil.Discard, "", 0))(w, r, sendable)
+		}
+
+		publicServer := httptest.NewServer(public)
+		defer publicServer.Close()
+		internalServer := httptest.NewServer(internal)
+		defer internalServer.Close()
+
+		Convey("should keep error verbosity separate per API", func() {
+			publicDoc, _, err := jsc.Fetch(publicServer.URL+public.prefix, testResourceType, "1")
+			So(err, ShouldBeNil)
+			So(publicDoc.Errors[0].Detail, ShouldEqual, jsh.DefaultErrorDetail)
+			So(publicDoc.JSONAPI, ShouldNotBeNil)
+
+			internalDoc, _, err := jsc.Fetch(internalServer.URL+internal.prefix, testResourceType, "1")
+			So(err, ShouldBeNil)
+			So(internalDoc.Errors[0].Detail, ShouldContainSubstring, "database unavailable")
+			So(internalDoc.JSONAPI, ShouldBeNil)
+			So(sent, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRecoverer(t *testing.T) {
+
+	Convey("Recoverer Tests", t, func() {
+
+		logs := &bytes.Buffer{}
+		logger := log.New(logs, "", 0)
+
+		resource := NewResource(testResourceType)
+		resource.Get(func(ctx context.Context, id string) (*jsh.Object, error) {
+			panic("storage exploded")
+		})
+
+		Convey("should send an ISE and log the stack", func() {
+			api := Default("", false, logger)
+			api.Add(resource)
+
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			doc, resp, err := jsc.Fetch(server.URL, testResourceType, "1")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+			So(doc.Errors[0].Code, ShouldEqual, jsh.CodeInternalServerError)
+			So(doc.Errors[0].Meta, ShouldBeNil)
+			So(logs.String(), ShouldContainSubstring, "storage exploded")
+			So(logs.String(), ShouldContainSubstring, "goroutine")
+		})
+
+		Convey("should include the stack in debug mode", func() {
+			api := Default("", true, logger)
+			api.Add(resource)
+
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			doc, _, err := jsc.Fetch(server.URL, testResourceType, "1")
+			So(err, ShouldBeNil)
+			So(doc.Errors[0].Meta["stack"], ShouldNotBeEmpty)
+		})
+	})
+}
+
+func TestRoutes(t *testing.T) {
+
+	Convey("Routes Tests", t, func() {
+
+		foos := NewResource("foos")
+		foos.ToOne("baz", func(ctx context.Context, id string) (*jsh.Object, error) {
+			return sampleObject("1", "baz", map[string]string{"baz": "ball"}), nil
+		})
+
+		bars := NewMockResource(testResourceType, 1, map[string]string{"foo": "bar"})
+		bars.Action("reset", func(ctx context.Context, id string) (*jsh.Object, error) {
+			return sampleObject(id, testResourceType, map[string]string{"foo": "bar"}), nil
+		})
+
+		api := New("api")
+		api.Add(foos)
+		api.Add(bars)
+
+		Convey("->Routes()", func() {
+
+			Convey("should list routes sorted by resource in registration order", func() {
+				So(api.Routes(), ShouldResemble, []Route{
+					{Method: get, Pattern: "/api/bars/:id", Resource: "bars", Kind: RouteGet},
+					{Method: post, Pattern: "/api/bars", Resource: "bars", Kind: RouteCreate},
+					{Method: get, Pattern: "/api/bars", Resource: "bars", Kind: RouteList},
+					{Method: patch, Pattern: "/api/bars/:id", Resource: "bars", Kind: RouteUpdate},
+					{Method: delete, Pattern: "/api/bars/:id", Resource: "bars", Kind: RouteDelete},
+					{Method: get, Pattern: "/api/bars/:id/reset", Resource: "bars", Kind: RouteAction},
+					{Method: get, Pattern: "/api/foos/:id/baz", Resource: "foos", Relationship: "baz", Kind: RouteRelated},
+					{Method: get, Pattern: "/api/foos/:id/relationships/baz", Resource: "foos", Relationship: "baz", Kind: RouteRelationship},
+				})
+			})
+
+			Convey("should build a stable route tree", func() {
+				So(api.RouteTree(), ShouldEqual, api.RouteTree())
+				So(api.RouteTree(), ShouldStartWith, "\nGET - /bars/:id")
+			})
+		})
+
+		Convey("->ServeHome()", func() {
+			api.Config = &jsh.Config{}
+			api.ServeHome()
+
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + "/api")
+			So(err, ShouldBeNil)
+
+			doc, parseErr := jsc.Document(resp, jsh.ObjectMode)
+			So(parseErr, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.Links.Self.HREF, ShouldEqual, "/api")
+			So(doc.JSONAPI, ShouldBeNil)
+			So(doc.Meta, ShouldResemble, map[string]interface{}{
+				"resources": map[string]interface{}{
+					"bars": map[string]interface{}{"href": "/api/bars"},
+					"foos": map[string]interface{}{"href": "/api/foos"},
+				},
+			})
+		})
+
+		Convey("should list the home and OpenAPI routes", func() {
+			api.ServeHome()
+			api.ServeOpenAPI("openapi.json")
+
+			So(api.Routes()[:2], ShouldResemble, []Route{
+				{Method: get, Pattern: "/api", Kind: RouteHome},
+				{Method: get, Pattern: "/api/openapi.json", Kind: RouteOpenAPI},
+			})
+			So(api.RouteTree(), ShouldStartWith, "\nGET - /api\nGET - /api/openapi.json\nGET - /bars/:id")
 		})
 	})
 }
@@ -0,0 +1,140 @@
+package jshapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+/*
+AttributeRule restricts who can see and write an attribute of a resource, see
+Resource.AttributeRules:
+
+	resource.AttributeRules["salary"] = jshapi.AttributeRule{
+		Visible:  isManager,
+		Writable: isManager,
+	}
+	resource.AttributeRules["created_at"] = jshapi.AttributeRule{ReadOnly: true}
+*/
+type AttributeRule struct {
+	// Visible decides whether the attribute is sent to the caller, it is always
+	// sent if nil. Lists can't be sorted or filtered by hidden attributes.
+	Visible func(ctx context.Context) bool
+	// ReadOnly rejects every write to the attribute
+	ReadOnly bool
+	// Writable decides whether the caller may write the attribute, it is always
+	// writable if nil unless ReadOnly is set
+	Writable func(ctx context.Context) bool
+}
+
+// visible checks whether the attribute can be sent within ctx
+func (rule AttributeRule) visible(ctx context.Context) bool {
+	return rule.Visible == nil || rule.Visible(ctx)
+}
+
+// writable checks whether the attribute can be written within ctx
+func (rule AttributeRule) writable(ctx context.Context) bool {
+	if rule.ReadOnly {
+		return false
+	}
+
+	return rule.Writable == nil || rule.Writable(ctx)
+}
+
+/*
+checkWritable rejects objects that set attributes the caller may not write with
+a 403 for each of them, pointing at "/data/attributes/<attribute>".
+*/
+func (res *Resource) checkWritable(ctx context.Context, object *jsh.Object) jsh.ErrorType {
+	if len(res.AttributeRules) == 0 || len(object.Attributes) == 0 {
+		return nil
+	}
+
+	attributes := map[string]json.RawMessage{}
+
+	err := json.Unmarshal(object.Attributes, &attributes)
+	if err != nil {
+		return jsh.InputError("Attributes must be an object", "attributes")
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errors := jsh.ErrorList{}
+	for _, name := range names {
+		rule, exists := res.AttributeRules[name]
+		if !exists || rule.writable(ctx) {
+			continue
+		}
+
+		forbidden := jsh.Forbidden(fmt.Sprintf("Attribute '%s' cannot be written", name))
+		forbidden.Source.Pointer = "/data/attributes/" + name
+		errors = append(errors, forbidden)
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+// attributeVisible checks whether the caller may see the attribute, hidden
+// attributes can't be used to sort or filter either
+func (res *Resource) attributeVisible(ctx context.Context, name string) bool {
+	rule, exists := res.AttributeRules[name]
+	return !exists || rule.visible(ctx)
+}
+
+/*
+hideAttributes returns a copy of the object without the attributes the caller may
+not see, see AttributeRule. The object itself is returned when nothing is hidden.
+Objects with attributes that can't be decoded result in an ISE, rather than
+being sent with hidden attributes.
+*/
+func (res *Resource) hideAttributes(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if object == nil || len(object.Attributes) == 0 {
+		return object, nil
+	}
+
+	hidden := map[string]bool{}
+	for name, rule := range res.AttributeRules {
+		if !rule.visible(ctx) {
+			hidden[name] = true
+		}
+	}
+
+	if len(hidden) == 0 {
+		return object, nil
+	}
+
+	attributes := map[string]json.RawMessage{}
+
+	err := json.Unmarshal(object.Attributes, &attributes)
+	if err != nil {
+		return nil, jsh.ISE(fmt.Sprintf("Unable to hide attributes of '%s' %s: %s", object.Type, object.ID, err.Error()))
+	}
+
+	visible := map[string]json.RawMessage{}
+	for name, value := range attributes {
+		if !hidden[name] {
+			visible[name] = value
+		}
+	}
+
+	raw, err := json.Marshal(visible)
+	if err != nil {
+		return nil, jsh.ISE(fmt.Sprintf("Unable to hide attributes of '%s' %s: %s", object.Type, object.ID, err.Error()))
+	}
+
+	copied := *object
+	copied.Attributes = raw
+
+	return &copied, nil
+}
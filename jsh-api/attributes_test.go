@@ -0,0 +1,138 @@
+package jshapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store/memory"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type roleKey struct{}
+
+func TestAttributeRules(t *testing.T) {
+
+	isManager := func(ctx context.Context) bool {
+		return ctx.Value(roleKey{}) == "manager"
+	}
+
+	employees := memory.New("employees")
+	object, _ := jsh.NewObject("1", "employees", map[string]interface{}{
+		"name":       "Ann",
+		"salary":     100,
+		"created_at": "2016-01-01",
+	})
+	employees.Save(context.Background(), object)
+
+	resource := NewCRUDResource("employees", employees)
+	resource.AttributeRules["salary"] = AttributeRule{Visible: isManager, Writable: isManager}
+	resource.AttributeRules["created_at"] = AttributeRule{ReadOnly: true}
+
+	api := New("")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), roleKey{}, r.Header.Get("X-Role"))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	fetch := func(role string) map[string]interface{} {
+		request, err := jsc.FetchRequest(server.URL, "employees", "1")
+		So(err, ShouldBeNil)
+		request.Header.Set("X-Role", role)
+
+		doc, _, err := jsc.Do(request, jsh.ObjectMode)
+		So(err, ShouldBeNil)
+
+		attributes := map[string]interface{}{}
+		So(doc.First().Unmarshal("employees", &attributes), ShouldBeNil)
+		return attributes
+	}
+
+	patch := func(role string, attributes map[string]interface{}) (*jsh.Document, *http.Response) {
+		object, _ := jsh.NewObject("1", "employees", attributes)
+
+		request, err := jsc.PatchRequest(server.URL, object)
+		So(err, ShouldBeNil)
+		request.Header.Set("X-Role", role)
+
+		doc, resp, err := jsc.Do(request, jsh.ObjectMode)
+		So(err, ShouldBeNil)
+		return doc, resp
+	}
+
+	Convey("Attribute Rule Tests", t, func() {
+
+		Convey("should hide attributes from callers that may not see them", func() {
+			So(fetch(""), ShouldNotContainKey, "salary")
+			So(fetch("manager"), ShouldContainKey, "salary")
+			So(fetch(""), ShouldContainKey, "created_at")
+		})
+
+		Convey("should hide attributes on a copy of the object", func() {
+			object, _ := jsh.NewObject("2", "employees", map[string]interface{}{"name": "Bob", "salary": 50})
+
+			hidden, err := resource.hideAttributes(context.Background(), object)
+			So(err, ShouldBeNil)
+			So(string(hidden.Attributes), ShouldEqual, `{"name":"Bob"}`)
+			So(string(object.Attributes), ShouldContainSubstring, "salary")
+		})
+
+		Convey("should fail rather than send attributes that can't be hidden", func() {
+			object := &jsh.Object{ID: "2", Type: "employees", Attributes: []byte(`["salary"]`)}
+
+			hidden, err := resource.hideAttributes(context.Background(), object)
+			So(hidden, ShouldBeNil)
+			So(err.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("should reject writes to restricted attributes", func() {
+			doc, resp := patch("", map[string]interface{}{"salary": 200, "created_at": "2017-01-01"})
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+			So(len(doc.Errors), ShouldEqual, 2)
+			So(doc.Errors[0].Source.Pointer, ShouldEqual, "/data/attributes/created_at")
+			So(doc.Errors[1].Source.Pointer, ShouldEqual, "/data/attributes/salary")
+
+			_, resp = patch("manager", map[string]interface{}{"created_at": "2017-01-01"})
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("should allow permitted writes", func() {
+			_, resp := patch("manager", map[string]interface{}{"salary": 200})
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(fetch("manager")["salary"], ShouldEqual, 200)
+		})
+
+		Convey("should reject sorting and filtering by hidden attributes", func() {
+			list := func(role string, rawQuery string) (*jsh.Document, *http.Response) {
+				request, err := jsc.ListRequest(server.URL, "employees")
+				So(err, ShouldBeNil)
+				request.URL.RawQuery = rawQuery
+				request.Header.Set("X-Role", role)
+
+				doc, resp, err := jsc.Do(request, jsh.ListMode)
+				So(err, ShouldBeNil)
+				return doc, resp
+			}
+
+			doc, resp := list("", "sort=-salary")
+			So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(doc.Errors[0].Source.Parameter, ShouldEqual, sortParam)
+
+			doc, resp = list("", "filter[salary]=100")
+			So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(doc.Errors[0].Source.Parameter, ShouldEqual, "filter[salary]")
+
+			_, resp = list("manager", "sort=-salary&filter[salary]=100")
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+		})
+	})
+}
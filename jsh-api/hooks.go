@@ -0,0 +1,121 @@
+package jshapi
+
+import (
+	"context"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+/*
+Hook intercepts an object passed to or returned from storage. Hooks may modify
+the object, or abort the request by returning an error which is sent like a
+storage error, preferably a jsh.ErrorType:
+
+	resource.BeforeSave(func(ctx context.Context, object *jsh.Object) error {
+		object.Meta = map[string]interface{}{"created": time.Now()}
+		return nil
+	})
+*/
+type Hook func(ctx context.Context, object *jsh.Object) error
+
+// DeleteHook intercepts the id of a resource being deleted, see Hook
+type DeleteHook func(ctx context.Context, id string) error
+
+/*
+hooks holds the chains registered to a resource. For each request, the Before
+hooks run in registration order ahead of storage, followed by the After hooks in
+registration order once storage succeeded. The first error stops the chain, so
+neither storage nor the remaining hooks are called. An error from an After hook
+is sent in place of the response, even though storage already made its change.
+*/
+type hooks struct {
+	beforeSave   []Hook
+	afterSave    []Hook
+	beforeUpdate []Hook
+	afterUpdate  []Hook
+	beforeDelete []DeleteHook
+	afterDelete  []DeleteHook
+	afterFetch   []Hook
+}
+
+// BeforeSave registers hooks run on parsed objects before they are saved
+func (res *Resource) BeforeSave(hooks ...Hook) {
+	res.hooks.beforeSave = append(res.hooks.beforeSave, hooks...)
+}
+
+// AfterSave registers hooks run on saved objects before they are sent
+func (res *Resource) AfterSave(hooks ...Hook) {
+	res.hooks.afterSave = append(res.hooks.afterSave, hooks...)
+}
+
+// BeforeUpdate registers hooks run on parsed objects before they are updated
+func (res *Resource) BeforeUpdate(hooks ...Hook) {
+	res.hooks.beforeUpdate = append(res.hooks.beforeUpdate, hooks...)
+}
+
+// AfterUpdate registers hooks run on updated objects before they are sent
+func (res *Resource) AfterUpdate(hooks ...Hook) {
+	res.hooks.afterUpdate = append(res.hooks.afterUpdate, hooks...)
+}
+
+// BeforeDelete registers hooks run before a resource is deleted
+func (res *Resource) BeforeDelete(hooks ...DeleteHook) {
+	res.hooks.beforeDelete = append(res.hooks.beforeDelete, hooks...)
+}
+
+// AfterDelete registers hooks run once a resource has been deleted
+func (res *Resource) AfterDelete(hooks ...DeleteHook) {
+	res.hooks.afterDelete = append(res.hooks.afterDelete, hooks...)
+}
+
+// AfterFetch registers hooks run on every object of the resource's type sent by
+// GET requests, whether as primary data, as related resources, or as included
+// resources, before they are sent
+func (res *Resource) AfterFetch(hooks ...Hook) {
+	res.hooks.afterFetch = append(res.hooks.afterFetch, hooks...)
+}
+
+// runHooks runs a chain of hooks on each object, stopping at the first error
+func runHooks(ctx context.Context, hooks []Hook, objects ...*jsh.Object) jsh.ErrorType {
+	for _, object := range objects {
+		for _, hook := range hooks {
+			if err := jsh.MapError(hook(ctx, object)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// afterFetch runs the AfterFetch hooks of the resource serving each object's type
+// on related and included objects
+func (a *API) afterFetch(ctx context.Context, objects ...*jsh.Object) jsh.ErrorType {
+	if a == nil {
+		return nil
+	}
+
+	for _, object := range objects {
+		resource, served := a.Resources[object.Type]
+		if !served {
+			continue
+		}
+
+		if err := runHooks(ctx, resource.hooks.afterFetch, object); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runDeleteHooks runs a chain of delete hooks, stopping at the first error
+func runDeleteHooks(ctx context.Context, hooks []DeleteHook, id string) jsh.ErrorType {
+	for _, hook := range hooks {
+		if err := jsh.MapError(hook(ctx, id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
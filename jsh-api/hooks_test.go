@@ -0,0 +1,135 @@
+package jshapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store/memory"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHooks(t *testing.T) {
+
+	calls := []string{}
+
+	record := func(name string) Hook {
+		return func(ctx context.Context, object *jsh.Object) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	posts := memory.New("posts")
+
+	resource := NewCRUDResource("posts", posts)
+	resource.BeforeSave(record("before save 1"), func(ctx context.Context, object *jsh.Object) error {
+		calls = append(calls, "before save 2")
+		return object.Marshal(map[string]string{"title": "Hello", "slug": "hello"})
+	})
+	resource.AfterSave(record("after save"))
+	resource.BeforeUpdate(func(ctx context.Context, object *jsh.Object) error {
+		return jsh.InputError("Posts are read only", "title")
+	})
+	resource.BeforeDelete(func(ctx context.Context, id string) error {
+		calls = append(calls, "before delete "+id)
+		return nil
+	})
+	resource.AfterDelete(func(ctx context.Context, id string) error {
+		calls = append(calls, "after delete "+id)
+		return nil
+	})
+	resource.AfterFetch(func(ctx context.Context, object *jsh.Object) error {
+		object.Meta = map[string]interface{}{"fetched": true}
+		return nil
+	})
+
+	// comments relate to every post, so that hooks can be checked on related
+	// and included posts
+	comments := NewResource("comments")
+	comments.Get(func(ctx context.Context, id string) (*jsh.Object, error) {
+		return sampleObject(id, "comments", map[string]string{"body": "Hi"}), nil
+	})
+	comments.ToOne("post", func(ctx context.Context, id string) (*jsh.Object, error) {
+		list, err := posts.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return list[0], nil
+	})
+	comments.ToMany("post", func(ctx context.Context, id string) (jsh.List, error) {
+		return posts.List(ctx)
+	})
+
+	api := New("")
+	api.Add(resource)
+	api.Add(comments)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	Convey("Hook Tests", t, func() {
+
+		Reset(func() {
+			calls = []string{}
+		})
+
+		object, _ := jsh.NewObject("", "posts", map[string]string{"title": "Hello"})
+		saved, resp, err := jsc.Post(server.URL, object)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+
+		Convey("should run save hooks in order and keep their changes", func() {
+			So(calls, ShouldResemble, []string{"before save 1", "before save 2", "after save"})
+
+			attributes := map[string]string{}
+			So(saved.First().Unmarshal("posts", &attributes), ShouldBeNil)
+			So(attributes["slug"], ShouldEqual, "hello")
+		})
+
+		Convey("should abort requests with hook errors", func() {
+			doc, resp, err := jsc.Patch(server.URL, saved.First())
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 422)
+			So(doc.Errors[0].Detail, ShouldEqual, "Posts are read only")
+		})
+
+		Convey("should run fetch hooks on every object", func() {
+			doc, _, err := jsc.Fetch(server.URL, "posts", saved.First().ID)
+			So(err, ShouldBeNil)
+			So(doc.First().Meta["fetched"], ShouldBeTrue)
+
+			doc, _, err = jsc.List(server.URL, "posts")
+			So(err, ShouldBeNil)
+			So(doc.Data[0].Meta["fetched"], ShouldBeTrue)
+		})
+
+		Convey("should run fetch hooks on related and included objects", func() {
+			fetch := func(path string, mode jsh.DocumentMode) *jsh.Document {
+				request, err := jsc.NewRequest("GET", server.URL+path, nil)
+				So(err, ShouldBeNil)
+
+				doc, resp, err := jsc.Do(request, mode)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				return doc
+			}
+
+			So(fetch("/comments/1/post", jsh.ObjectMode).First().Meta["fetched"], ShouldBeTrue)
+			So(fetch("/comments/1/posts", jsh.ListMode).Data[0].Meta["fetched"], ShouldBeTrue)
+			So(fetch("/comments/1?include=post", jsh.ObjectMode).Included[0].Meta["fetched"], ShouldBeTrue)
+		})
+
+		Convey("should run delete hooks around storage", func() {
+			id := saved.First().ID
+
+			resp, err := jsc.Delete(server.URL, "posts", id)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
+			So(calls[len(calls)-2:], ShouldResemble, []string{"before delete " + id, "after delete " + id})
+		})
+	})
+}
@@ -0,0 +1,261 @@
+package jshapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+/*
+sendIncluded sends the primary data of a GET request along with the related
+resources requested via the "include" query parameter. Relationships are
+resolved using the storage registered via ToOne and ToMany on the resource that
+serves each object's type, nested paths are resolved recursively. Related objects
+are only included when the Policy of their type allows getting them. Lookups are
+batched per relationship and cached for the duration of the request.
+*/
+func (res *Resource) sendIncluded(w http.ResponseWriter, r *http.Request, payload jsh.Sendable) {
+	include, parseErr := jsh.ParseInclude(r)
+	if parseErr != nil {
+		res.send(w, r, parseErr)
+		return
+	}
+
+	if len(include) == 0 {
+		res.send(w, r, payload)
+		return
+	}
+
+	api := apiFromRequest(r)
+	document := api.config().Build(payload)
+	document.Status = http.StatusOK
+
+	included := newIncludedSet(document.Data)
+
+	err := api.include(r.Context(), res, document.Data, include, included, newLoader())
+	if err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	document.Included = included.objects
+	res.send(w, r, document)
+}
+
+/*
+include resolves the include tree for objects served by resource. The linkage
+of each included relationship is set on the objects, while the related objects
+are added to the included set. Each relationship is loaded for all objects at
+once.
+*/
+func (a *API) include(
+	ctx context.Context,
+	resource *Resource,
+	objects jsh.List,
+	include jsh.Include,
+	included *includedSet,
+	loader *loader,
+) jsh.ErrorType {
+
+	ids := make([]string, len(objects))
+	for i, object := range objects {
+		ids[i] = object.ID
+	}
+
+	for _, name := range include.Relationships() {
+		related := jsh.List{}
+
+		_, isToOne := resource.toOne[name]
+		_, isToMany := resource.toMany[name]
+
+		if !isToOne && !isToMany {
+			return jsh.ParameterError(
+				fmt.Sprintf("Relationship '%s' of resource '%s' cannot be included", name, resource.Type),
+				jsh.IncludeParam,
+			)
+		}
+
+		for _, object := range objects {
+			err := resource.checkPolicy(ctx, "", &PolicyRequest{
+				Action:       ActionGetRelationship,
+				ID:           object.ID,
+				Relationship: name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var toOne map[string]*jsh.Object
+		var toMany map[string]jsh.List
+		var err jsh.ErrorType
+
+		if isToOne {
+			toOne, err = loader.loadToOne(ctx, resource, name, ids)
+		} else {
+			toMany, err = loader.loadToMany(ctx, resource, name, ids)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		loaded := jsh.List{}
+		for _, object := range objects {
+			if isToOne {
+				if toOne[object.ID] != nil {
+					loaded = append(loaded, toOne[object.ID])
+				}
+			} else {
+				loaded = append(loaded, toMany[object.ID]...)
+			}
+		}
+
+		// related objects that their resource's Policy doesn't allow getting are
+		// neither linked nor included
+		hidden, err := a.hiddenRelated(ctx, loaded)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			relationship := &jsh.Relationship{Data: jsh.ResourceLinkage{}, ToOne: isToOne}
+
+			if isToOne {
+				relatedObject := toOne[object.ID]
+				if relatedObject != nil && !hidden[includedKey(relatedObject)] {
+					relationship.Data = append(relationship.Data, relatedObject.Identifier())
+					related = append(related, relatedObject)
+				}
+			} else {
+				list := withoutHidden(toMany[object.ID], hidden)
+				relationship.Data = list.Linkage()
+				related = append(related, list...)
+			}
+
+			setLinkage(object, name, relationship)
+		}
+
+		related = included.add(related)
+
+		if err := a.afterFetch(ctx, related...); err != nil {
+			return err
+		}
+
+		if len(include[name]) > 0 {
+			err := a.includeNested(ctx, name, related, include[name], included, loader)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// includeNested resolves a nested include path for related objects, grouped by
+// the resource that serves their type
+func (a *API) includeNested(
+	ctx context.Context,
+	name string,
+	related jsh.List,
+	include jsh.Include,
+	included *includedSet,
+	loader *loader,
+) jsh.ErrorType {
+
+	byType := map[string]jsh.List{}
+	types := []string{}
+	for _, object := range related {
+		if _, exists := byType[object.Type]; !exists {
+			types = append(types, object.Type)
+		}
+		byType[object.Type] = append(byType[object.Type], object)
+	}
+
+	for _, resourceType := range types {
+		resource, served := a.Resources[resourceType]
+		if !served {
+			return jsh.ParameterError(
+				fmt.Sprintf("Relationships of '%s' cannot be included", name),
+				jsh.IncludeParam,
+			)
+		}
+
+		err := a.include(ctx, resource, byType[resourceType], include, included, loader)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setLinkage sets the linkage of an object's relationship, keeping any existing
+// links and meta
+func setLinkage(object *jsh.Object, name string, linkage *jsh.Relationship) {
+	if object.Relationships == nil {
+		object.Relationships = map[string]*jsh.Relationship{}
+	}
+
+	existing, exists := object.Relationships[name]
+	if exists && existing != nil {
+		linkage.Links = existing.Links
+		linkage.Meta = existing.Meta
+	}
+
+	object.Relationships[name] = linkage
+}
+
+// includedSet deduplicates included objects by type and id, an object is never
+// included more than once, nor if it is part of the primary data
+type includedSet struct {
+	seen    map[string]*jsh.Object
+	objects []*jsh.Object
+}
+
+func newIncludedSet(primary jsh.List) *includedSet {
+	set := &includedSet{
+		seen:    map[string]*jsh.Object{},
+		objects: []*jsh.Object{},
+	}
+
+	for _, object := range primary {
+		set.seen[includedKey(object)] = object
+	}
+
+	return set
+}
+
+// add includes any objects not yet seen, returning the distinct related objects
+// with duplicates replaced by the instance that was seen first
+func (s *includedSet) add(objects jsh.List) jsh.List {
+	unique := jsh.List{}
+	added := map[string]bool{}
+
+	for _, object := range objects {
+		key := includedKey(object)
+		if added[key] {
+			continue
+		}
+		added[key] = true
+
+		seen, exists := s.seen[key]
+		if exists {
+			object = seen
+		} else {
+			s.seen[key] = object
+			s.objects = append(s.objects, object)
+		}
+
+		unique = append(unique, object)
+	}
+
+	return unique
+}
+
+func includedKey(object *jsh.Object) string {
+	return object.Type + "/" + object.ID
+}
@@ -0,0 +1,214 @@
+package jshapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInclude(t *testing.T) {
+
+	author := func(ctx context.Context, id string) (*jsh.Object, error) {
+		return sampleObject("9", "people", map[string]string{"name": "Dan"}), nil
+	}
+
+	articles := NewResource("articles")
+	articles.Get(func(ctx context.Context, id string) (*jsh.Object, error) {
+		return sampleObject(id, "articles", map[string]string{"title": "JSON API"}), nil
+	})
+	articles.List(func(ctx context.Context) (jsh.List, error) {
+		return jsh.List{
+			sampleObject("1", "articles", map[string]string{"title": "JSON API"}),
+			sampleObject("2", "articles", map[string]string{"title": "Go"}),
+		}, nil
+	})
+	articles.ToOne("author", author)
+	articles.ToMany("comments", func(ctx context.Context, id string) (jsh.List, error) {
+		return jsh.List{
+			sampleObject(id+"-1", "comments", map[string]string{"body": "First"}),
+			sampleObject(id+"-2", "comments", map[string]string{"body": "Second"}),
+		}, nil
+	})
+
+	comments := NewResource("comments")
+	comments.ToOne("author", author)
+
+	api := New("")
+	api.Add(articles)
+	api.Add(comments)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	fetch := func(path string, mode jsh.DocumentMode) (*jsh.Document, *http.Response) {
+		request, err := jsc.NewRequest("GET", server.URL+path, nil)
+		So(err, ShouldBeNil)
+
+		doc, resp, err := jsc.Do(request, mode)
+		So(err, ShouldBeNil)
+
+		return doc, resp
+	}
+
+	Convey("Include Tests", t, func() {
+
+		Convey("should include related resources and set linkage", func() {
+			doc, resp := fetch("/articles/1?include=author,comments.author", jsh.ObjectMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+			article := doc.First()
+			So(article.Relationships["author"].Data[0].ID, ShouldEqual, "9")
+			So(article.Relationships["author"].Links.Related.HREF, ShouldEqual, "/articles/1/author")
+			So(len(article.Relationships["comments"].Data), ShouldEqual, 2)
+
+			// the shared author is only included once
+			So(len(doc.Included), ShouldEqual, 3)
+			So(doc.Included[0].Type, ShouldEqual, "people")
+			So(doc.Included[1].Relationships["author"].Data[0].ID, ShouldEqual, "9")
+		})
+
+		Convey("should include related resources of a list", func() {
+			doc, resp := fetch("/articles?include=comments", jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Data), ShouldEqual, 2)
+			So(len(doc.Included), ShouldEqual, 4)
+		})
+
+		Convey("should reject relationships that cannot be included", func() {
+			doc, resp := fetch("/articles/1?include=editor", jsh.ObjectMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(doc.Errors[0].Source.Parameter, ShouldEqual, jsh.IncludeParam)
+		})
+	})
+}
+
+func TestBatchedInclude(t *testing.T) {
+
+	calls := map[string][][]string{}
+
+	articles := NewResource("articles")
+	articles.List(func(ctx context.Context) (jsh.List, error) {
+		return jsh.List{
+			sampleObject("1", "articles", map[string]string{"title": "JSON API"}),
+			sampleObject("2", "articles", map[string]string{"title": "Go"}),
+			sampleObject("3", "articles", map[string]string{"title": "Draft"}),
+		}, nil
+	})
+	articles.BatchToOne("author", func(ctx context.Context, ids []string) (map[string]*jsh.Object, error) {
+		calls["author"] = append(calls["author"], ids)
+
+		authors := map[string]*jsh.Object{}
+		for _, id := range ids {
+			if id != "3" {
+				authors[id] = sampleObject("9", "people", map[string]string{"name": "Dan"})
+			}
+		}
+		return authors, nil
+	})
+	articles.BatchToMany("comments", func(ctx context.Context, ids []string) (map[string]jsh.List, error) {
+		calls["comments"] = append(calls["comments"], ids)
+
+		lists := map[string]jsh.List{}
+		for _, id := range ids {
+			lists[id] = jsh.List{sampleObject(id+"-1", "comments", map[string]string{"body": "First"})}
+		}
+
+		// a typed nil error, as returned by storage using *jsh.Error results
+		var err *jsh.Error
+		return lists, err
+	})
+
+	api := New("")
+	api.Add(articles)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	Convey("Batched Include Tests", t, func() {
+
+		Reset(func() {
+			calls = map[string][][]string{}
+		})
+
+		Convey("should load each relationship of a list with a single call", func() {
+			request, err := jsc.NewRequest("GET", server.URL+"/articles?include=author,comments", nil)
+			So(err, ShouldBeNil)
+
+			doc, resp, err := jsc.Do(request, jsh.ListMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+			So(calls["author"], ShouldResemble, [][]string{{"1", "2", "3"}})
+			So(calls["comments"], ShouldResemble, [][]string{{"1", "2", "3"}})
+
+			So(len(doc.Data[2].Relationships["author"].Data), ShouldEqual, 0)
+			So(len(doc.Included), ShouldEqual, 4)
+		})
+
+		Convey("should serve relationship routes using batched storage", func() {
+			request, err := jsc.NewRequest("GET", server.URL+"/articles/1/author", nil)
+			So(err, ShouldBeNil)
+
+			doc, resp, err := jsc.Do(request, jsh.ObjectMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.First().ID, ShouldEqual, "9")
+			So(calls["author"], ShouldResemble, [][]string{{"1"}})
+
+			request, err = jsc.NewRequest("GET", server.URL+"/articles/3/author", nil)
+			So(err, ShouldBeNil)
+
+			doc, resp, err = jsc.Do(request, jsh.ObjectMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.HasData(), ShouldBeFalse)
+
+			request, err = jsc.NewRequest("GET", server.URL+"/articles/3/relationships/author", nil)
+			So(err, ShouldBeNil)
+
+			doc, resp, err = jsc.Do(request, jsh.LinkageMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.Linkage.Data, ShouldBeEmpty)
+
+			request, err = jsc.NewRequest("GET", server.URL+"/articles/1/comments", nil)
+			So(err, ShouldBeNil)
+
+			doc, resp, err = jsc.Do(request, jsh.ListMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.Data[0].ID, ShouldEqual, "1-1")
+		})
+	})
+}
+
+func TestLoader(t *testing.T) {
+
+	Convey("Loader Tests", t, func() {
+
+		calls := [][]string{}
+
+		resource := NewResource("articles")
+		resource.BatchToMany("comments", func(ctx context.Context, ids []string) (map[string]jsh.List, error) {
+			calls = append(calls, ids)
+			return map[string]jsh.List{}, nil
+		})
+
+		loader := newLoader()
+
+		Convey("should only load uncached and distinct ids", func() {
+			_, err := loader.loadToMany(context.Background(), resource, "comments", []string{"1", "2", "1"})
+			So(err, ShouldBeNil)
+
+			_, err = loader.loadToMany(context.Background(), resource, "comments", []string{"2", "3"})
+			So(err, ShouldBeNil)
+
+			So(calls, ShouldResemble, [][]string{{"1", "2"}, {"3"}})
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package jshapi
+
+import (
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+/*
+addLinks populates the "self" link of an object served by the API, along with the
+"self" and "related" links of every relationship registered to the resource that
+serves the object's type. Links that are already set are left untouched, as are
+objects of types that the API doesn't serve.
+*/
+func (a *API) addLinks(object *jsh.Object) {
+	if a == nil || object == nil || object.ID == "" {
+		return
+	}
+
+	resource, served := a.Resources[object.Type]
+	if !served {
+		return
+	}
+
+	if object.Links == nil {
+		object.Links = map[string]*jsh.Link{}
+	}
+
+	if _, exists := object.Links["self"]; !exists {
+		object.Links["self"] = jsh.NewLink(a.resourcePath(object.Type, object.ID))
+	}
+
+	for name := range resource.Relationships {
+		if object.Relationships == nil {
+			object.Relationships = map[string]*jsh.Relationship{}
+		}
+
+		relationship := object.Relationships[name]
+		if relationship == nil {
+			relationship = &jsh.Relationship{}
+			object.Relationships[name] = relationship
+		}
+
+		if relationship.Links == nil {
+			relationship.Links = resource.relationshipLinks(a, object.ID, name)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package jshapi
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLinks(t *testing.T) {
+
+	resource := NewMockResource(testResourceType, 2, testObjAttrs)
+	resource.ToOne("baz", func(ctx context.Context, id string) (*jsh.Object, error) {
+		return sampleObject("1", "baz", map[string]string{"baz": "ball"}), nil
+	})
+
+	api := New("api")
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	baseURL := server.URL + api.prefix
+
+	Convey("Link Tests", t, func() {
+
+		Convey("should add self and relationship links to objects", func() {
+			doc, _, err := jsc.Fetch(baseURL, testResourceType, "3")
+			So(err, ShouldBeNil)
+
+			object := doc.First()
+			So(object.Links["self"].HREF, ShouldEqual, "/api/bars/3")
+			So(object.Relationships["baz"].Links.Self.HREF, ShouldEqual, "/api/bars/3/relationships/baz")
+			So(object.Relationships["baz"].Links.Related.HREF, ShouldEqual, "/api/bars/3/baz")
+		})
+
+		Convey("should add links to every object in a list", func() {
+			doc, _, err := jsc.List(baseURL, testResourceType)
+			So(err, ShouldBeNil)
+
+			for _, object := range doc.Data {
+				So(object.Links["self"].HREF, ShouldEqual, "/api/bars/"+object.ID)
+			}
+		})
+
+		Convey("should leave objects of types the API doesn't serve alone", func() {
+			doc, _, err := jsc.Action(baseURL, testResourceType, "1", "baz")
+			So(err, ShouldBeNil)
+			So(doc.First().Links, ShouldBeEmpty)
+		})
+
+		Convey("should not overwrite existing links", func() {
+			object := sampleObject("1", testResourceType, testObjAttrs)
+			object.Links["self"] = jsh.NewLink("http://example.com/bars/1")
+
+			api.addLinks(object)
+			So(object.Links["self"].HREF, ShouldEqual, "http://example.com/bars/1")
+		})
+	})
+}
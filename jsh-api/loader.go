@@ -0,0 +1,183 @@
+package jshapi
+
+import (
+	"context"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+/*
+loader coalesces the relationship lookups made while resolving includes for a
+single request. Each lookup covers all parent ids at once, and results are
+cached so that a relationship is never loaded twice for the same parent.
+*/
+type loader struct {
+	toOne  map[string]map[string]*jsh.Object
+	toMany map[string]map[string]jsh.List
+}
+
+func newLoader() *loader {
+	return &loader{
+		toOne:  map[string]map[string]*jsh.Object{},
+		toMany: map[string]map[string]jsh.List{},
+	}
+}
+
+// loadToOne returns the related object of each parent id, nil entries mean that
+// no object is related
+func (l *loader) loadToOne(
+	ctx context.Context,
+	resource *Resource,
+	name string,
+	ids []string,
+) (map[string]*jsh.Object, jsh.ErrorType) {
+
+	key := resource.Type + "." + name
+	cache, exists := l.toOne[key]
+	if !exists {
+		cache = map[string]*jsh.Object{}
+		l.toOne[key] = cache
+	}
+
+	missing := []string{}
+	for _, id := range uniqueIDs(ids) {
+		if _, cached := cache[id]; !cached {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded, storageErr := resource.toOne[name](ctx, missing)
+		if err := jsh.MapError(storageErr); err != nil {
+			return nil, err
+		}
+
+		for _, id := range missing {
+			cache[id] = loaded[id]
+		}
+	}
+
+	return cache, nil
+}
+
+// loadToMany returns the related objects of each parent id
+func (l *loader) loadToMany(
+	ctx context.Context,
+	resource *Resource,
+	name string,
+	ids []string,
+) (map[string]jsh.List, jsh.ErrorType) {
+
+	key := resource.Type + "." + name
+	cache, exists := l.toMany[key]
+	if !exists {
+		cache = map[string]jsh.List{}
+		l.toMany[key] = cache
+	}
+
+	missing := []string{}
+	for _, id := range uniqueIDs(ids) {
+		if _, cached := cache[id]; !cached {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded, storageErr := resource.toMany[name](ctx, missing)
+		if err := jsh.MapError(storageErr); err != nil {
+			return nil, err
+		}
+
+		for _, id := range missing {
+			cache[id] = loaded[id]
+		}
+	}
+
+	return cache, nil
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// getManyFromGet adapts a single object lookup for batched loading, calling it
+// once per id
+func getManyFromGet(storage store.Get) store.GetMany {
+	return func(ctx context.Context, ids []string) (map[string]*jsh.Object, error) {
+		objects := map[string]*jsh.Object{}
+
+		for _, id := range ids {
+			object, storageErr := storage(ctx, id)
+			if err := jsh.MapError(storageErr); err != nil {
+				return nil, err
+			}
+
+			if object != nil {
+				objects[id] = object
+			}
+		}
+
+		return objects, nil
+	}
+}
+
+// getFromGetMany adapts batched storage for looking up a single object, a missing
+// object is an empty relationship
+func getFromGetMany(storage store.GetMany) store.Get {
+	return func(ctx context.Context, id string) (*jsh.Object, error) {
+		objects, storageErr := storage(ctx, []string{id})
+		if err := jsh.MapError(storageErr); err != nil {
+			return nil, err
+		}
+
+		return objects[id], nil
+	}
+}
+
+// toManyBatchFromToMany adapts a single relationship lookup for batched loading,
+// calling it once per id
+func toManyBatchFromToMany(storage store.ToMany) store.ToManyBatch {
+	return func(ctx context.Context, ids []string) (map[string]jsh.List, error) {
+		lists := map[string]jsh.List{}
+
+		for _, id := range ids {
+			list, storageErr := storage(ctx, id)
+			if err := jsh.MapError(storageErr); err != nil {
+				return nil, err
+			}
+
+			lists[id] = list
+		}
+
+		return lists, nil
+	}
+}
+
+// toManyFromBatch adapts batched storage for looking up the related objects of a
+// single resource
+func toManyFromBatch(storage store.ToManyBatch) store.ToMany {
+	return func(ctx context.Context, id string) (jsh.List, error) {
+		lists, storageErr := storage(ctx, []string{id})
+		if err := jsh.MapError(storageErr); err != nil {
+			return nil, err
+		}
+
+		list := lists[id]
+		if list == nil {
+			list = jsh.List{}
+		}
+
+		return list, nil
+	}
+}
@@ -21,40 +21,30 @@ type MockStorage struct {
 }
 
 // Save assigns a URL of 1 to the object
-func (m *MockStorage) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
-	var err *jsh.Error
+func (m *MockStorage) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
 	object.ID = "1"
 
-	return object, err
+	return object, nil
 }
 
 // Get returns a resource with ID as specified by the request
-func (m *MockStorage) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
-	var err *jsh.Error
-
-	return m.SampleObject(id), err
+func (m *MockStorage) Get(ctx context.Context, id string) (*jsh.Object, error) {
+	return m.SampleObject(id), nil
 }
 
 // List returns a sample list
-func (m *MockStorage) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
-	var err *jsh.Error
-
-	return m.SampleList(m.ListCount), err
+func (m *MockStorage) List(ctx context.Context) (jsh.List, error) {
+	return m.SampleList(m.ListCount), nil
 }
 
 // Update does nothing
-func (m *MockStorage) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
-	var err jsh.ErrorList
-	err = nil
-
-	return object, err
+func (m *MockStorage) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	return object, nil
 }
 
 // Delete does nothing
-func (m *MockStorage) Delete(ctx context.Context, id string) jsh.ErrorType {
-	var err *jsh.Error
-
-	return err
+func (m *MockStorage) Delete(ctx context.Context, id string) error {
+	return nil
 }
 
 // SampleObject builds an object based on provided resource specifications
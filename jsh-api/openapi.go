@@ -0,0 +1,644 @@
+package jshapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"goji.io/pat"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification generated by OpenAPI
+const OpenAPIVersion = "3.1.0"
+
+// openAPIComponent matches characters that aren't allowed in component names
+var openAPIComponent = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+/*
+OpenAPI generates an OpenAPI 3.1 document describing every route registered to
+the API's resources. Request and response bodies are described as JSON API
+documents, using each resource's Schema for its attributes and relationships
+when set. List operations document the include and fields parameters, as well
+as sort, filter and page when served by ListWithQuery storage.
+
+The document is a plain map so that it can be extended before being encoded:
+
+	doc := api.OpenAPI()
+	doc["servers"] = []map[string]interface{}{{"url": "https://api.example.com"}}
+*/
+func (a *API) OpenAPI() map[string]interface{} {
+	title := a.Title
+	if title == "" {
+		title = "JSON API"
+	}
+
+	version := a.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	paths := map[string]interface{}{}
+	schemas := openAPIBaseSchemas()
+	tags := []interface{}{}
+
+	for _, resourceType := range a.resourceTypes() {
+		res := a.Resources[resourceType]
+		tags = append(tags, map[string]interface{}{"name": res.Type})
+
+		for name, schema := range res.openAPISchemas() {
+			schemas[name] = schema
+		}
+
+		for matcher, operations := range res.openAPIOperations(a) {
+			paths[a.openAPIPath(res.Type, matcher)] = operations
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas":    schemas,
+			"parameters": openAPIParameters(),
+			"responses":  openAPIErrorResponses(),
+		},
+	}
+}
+
+/*
+ServeOpenAPI serves the API's OpenAPI document via GET at docPath, relative to the
+API's prefix. The document is generated on every request so that it reflects
+resources added later on.
+*/
+func (a *API) ServeOpenAPI(docPath string) {
+	docPath = path.Join(a.prefix, docPath)
+	a.addRoute(Route{Method: get, Pattern: docPath, Kind: RouteOpenAPI})
+
+	a.Mux.HandleFunc(pat.Get(docPath), func(w http.ResponseWriter, r *http.Request) {
+		content, err := json.Marshal(a.OpenAPI())
+		if err != nil {
+			a.sender()(w, r, jsh.ISE(fmt.Sprintf("Unable to encode OpenAPI document: %s", err.Error())))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	})
+}
+
+// openAPIPath converts a resource's route pattern into an OpenAPI path template
+func (a *API) openAPIPath(resourceType string, matcher string) string {
+	return a.resourcePath(resourceType, strings.Replace(matcher, ":id", "{id}", 1))
+}
+
+// openAPIOperations builds the operations of every route registered to the
+// resource, keyed by route pattern
+func (res *Resource) openAPIOperations(api *API) map[string]interface{} {
+	paths := map[string]interface{}{}
+
+	for matcher, methods := range res.methods {
+		operations := map[string]interface{}{}
+		for _, method := range methods {
+			operations[strings.ToLower(method)] = res.openAPIOperation(api, method, matcher)
+		}
+
+		if strings.HasPrefix(matcher, patID) {
+			operations["parameters"] = []interface{}{openAPIRef("parameters", "id")}
+		}
+
+		paths[matcher] = operations
+	}
+
+	return paths
+}
+
+// openAPIOperation describes a single method of a route pattern
+func (res *Resource) openAPIOperation(api *API, method string, matcher string) map[string]interface{} {
+	component := openAPIName(res.Type)
+	operation := map[string]interface{}{"tags": []string{res.Type}}
+	responses := map[string]interface{}{}
+	errors := []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound}
+
+	segments := strings.Split(strings.TrimPrefix(matcher, patID), "/")
+	switch {
+	case matcher == patRoot && method == get:
+		operation["summary"] = fmt.Sprintf("List %s", res.Type)
+		operation["parameters"] = res.openAPIListParameters()
+		responses["200"] = openAPIDocument("List of "+res.Type, component+"ListDocument")
+	case matcher == patRoot && method == post:
+		operation["summary"] = fmt.Sprintf("Create a %s resource", res.Type)
+		operation["requestBody"] = openAPIRequest(component + "Request")
+		responses["201"] = openAPIDocument("Created "+res.Type, component+"Document")
+		errors = append(errors, http.StatusConflict, http.StatusUnprocessableEntity)
+	case matcher == patID:
+		res.openAPIObjectOperation(operation, responses, &errors, method, component)
+	case len(segments) == 3 && segments[1] == "relationships":
+		name := segments[2]
+		linkage := "ToOneLinkage"
+		if res.Relationships[name] == ToMany {
+			linkage = "ToManyLinkage"
+		}
+
+		operation["summary"] = fmt.Sprintf("%s %s linkage", openAPIVerb(method), name)
+		if method != get {
+			operation["requestBody"] = openAPIRequest(linkage)
+			errors = append(errors, http.StatusConflict, http.StatusUnprocessableEntity)
+		}
+		responses["200"] = openAPIDocument("Resource linkage of "+name, linkage)
+	case len(segments) == 2 && res.Relationships[segments[1]] != "":
+		name := segments[1]
+		related := "ResourceDocument"
+		if res.Relationships[name] == ToMany {
+			related = "ResourceListDocument"
+		}
+		// use the related resource's schemas if the API serves it
+		if relationship, exists := res.relationshipSchema(name); exists && api.Resources[relationship.Type] != nil {
+			related = openAPIName(relationship.Type) + strings.TrimPrefix(related, "Resource")
+		}
+
+		operation["summary"] = fmt.Sprintf("Get related %s", name)
+		responses["200"] = openAPIDocument("Related "+name, related)
+	default:
+		operation["summary"] = fmt.Sprintf("Run the %s action", segments[len(segments)-1])
+		responses["200"] = openAPIDocument("Action result", "ResourceDocument")
+		if method != get {
+			operation["requestBody"] = map[string]interface{}{
+				"required": false,
+				"content": map[string]interface{}{
+					jsh.ContentType: map[string]interface{}{"schema": openAPIRef("schemas", "ActionDocument")},
+				},
+			}
+			responses["202"] = openAPIDocument("Action accepted", "ActionDocument")
+			responses["204"] = map[string]interface{}{"description": "Action completed"}
+		}
+	}
+
+	if method == get {
+		operation["parameters"] = append(openAPIList(operation["parameters"]), openAPIRef("parameters", "include"))
+	}
+
+	operation["operationId"] = res.openAPIOperationID(method, matcher)
+	for _, status := range append(errors, http.StatusInternalServerError) {
+		responses[fmt.Sprintf("%d", status)] = openAPIRef("responses", fmt.Sprintf("Error%d", status))
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// openAPIObjectOperation describes GET, PATCH and DELETE /resources/:id
+func (res *Resource) openAPIObjectOperation(
+	operation map[string]interface{},
+	responses map[string]interface{},
+	errors *[]int,
+	method string,
+	component string,
+) {
+	switch method {
+	case get:
+		operation["summary"] = fmt.Sprintf("Get a %s resource", res.Type)
+		operation["parameters"] = []interface{}{openAPIRef("parameters", "fields")}
+		responses["200"] = openAPIDocument("The "+res.Type+" resource", component+"Document")
+		return
+	case patch:
+		operation["summary"] = fmt.Sprintf("Update a %s resource", res.Type)
+		operation["requestBody"] = openAPIRequest(component + "Request")
+		responses["200"] = openAPIDocument("Updated "+res.Type, component+"Document")
+		*errors = append(*errors, http.StatusConflict, http.StatusUnprocessableEntity)
+	case delete:
+		operation["summary"] = fmt.Sprintf("Delete a %s resource", res.Type)
+		responses["204"] = map[string]interface{}{"description": "Deleted"}
+	}
+
+	if res.RequireIfMatch {
+		operation["parameters"] = []interface{}{openAPIRef("parameters", "ifMatch")}
+		*errors = append(*errors, http.StatusPreconditionFailed, http.StatusPreconditionRequired)
+	}
+}
+
+// openAPIListParameters returns the query parameters supported by the resource's
+// list route
+func (res *Resource) openAPIListParameters() []interface{} {
+	parameters := []interface{}{openAPIRef("parameters", "fields")}
+	if !res.queryList {
+		return parameters
+	}
+
+	filters := map[string]interface{}{}
+	if res.Schema != nil {
+		for _, attribute := range res.Schema.Attributes {
+			if res.filterable(attribute.Name) {
+				filters[attribute.Name] = map[string]interface{}{"type": "string"}
+			}
+		}
+	}
+
+	filter := map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}
+	if len(filters) > 0 {
+		filter = map[string]interface{}{"type": "object", "properties": filters, "additionalProperties": false}
+	}
+
+	return append(parameters,
+		openAPIRef("parameters", "sort"),
+		map[string]interface{}{
+			"name":    filterParam,
+			"in":      "query",
+			"style":   "deepObject",
+			"explode": true,
+			"schema":  filter,
+		},
+		openAPIRef("parameters", "pageNumber"),
+		openAPIRef("parameters", "pageSize"),
+		openAPIRef("parameters", "pageCursor"),
+	)
+}
+
+// openAPIOperationID builds a unique operation ID such as "articles.comments.get"
+func (res *Resource) openAPIOperationID(method string, matcher string) string {
+	segments := []string{res.Type}
+	for _, segment := range strings.Split(strings.TrimPrefix(matcher, patID), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	action := strings.ToLower(method)
+	switch {
+	case matcher == patRoot && method == get:
+		action = "list"
+	case matcher == patRoot && method == post:
+		action = "create"
+	case method == patch:
+		action = "update"
+	}
+
+	return strings.Join(append(segments, action), ".")
+}
+
+// openAPISchemas builds the component schemas of the resource
+func (res *Resource) openAPISchemas() map[string]interface{} {
+	component := openAPIName(res.Type)
+
+	attributes := map[string]interface{}{"type": "object"}
+	if res.Schema != nil {
+		properties := map[string]interface{}{}
+		required := []string{}
+		for _, attribute := range res.Schema.Attributes {
+			properties[attribute.Name] = openAPIAttribute(attribute)
+			if attribute.Required {
+				required = append(required, attribute.Name)
+			}
+		}
+
+		attributes["properties"] = properties
+		if len(required) > 0 {
+			attributes["required"] = required
+		}
+	}
+
+	relationships := map[string]interface{}{}
+	for _, name := range res.relationshipNames() {
+		linkage := "ToOneLinkage"
+		if res.Relationships[name] == ToMany {
+			linkage = "ToManyLinkage"
+		}
+		if relationship, exists := res.relationshipSchema(name); exists && relationship.ToMany {
+			linkage = "ToManyLinkage"
+		}
+
+		relationships[name] = openAPIRef("schemas", linkage)
+	}
+
+	object := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"type", "id"},
+		"properties": map[string]interface{}{
+			"type":          map[string]interface{}{"const": res.Type},
+			"id":            map[string]interface{}{"type": "string"},
+			"attributes":    openAPIRef("schemas", component+"Attributes"),
+			"relationships": map[string]interface{}{"type": "object", "properties": relationships},
+			"links":         openAPIRef("schemas", "Links"),
+			"meta":          openAPIRef("schemas", "Meta"),
+		},
+	}
+
+	request := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"data"},
+		"properties": map[string]interface{}{
+			"data": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"type"},
+				"properties": map[string]interface{}{
+					"type":          map[string]interface{}{"const": res.Type},
+					"id":            map[string]interface{}{"type": "string"},
+					"attributes":    openAPIRef("schemas", component+"Attributes"),
+					"relationships": map[string]interface{}{"type": "object", "properties": relationships},
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		component + "Attributes":   attributes,
+		component + "Resource":     object,
+		component + "Document":     openAPIDocumentSchema(openAPIRef("schemas", component+"Resource")),
+		component + "ListDocument": openAPIDocumentSchema(openAPIArray(openAPIRef("schemas", component+"Resource"))),
+		component + "Request":      request,
+	}
+}
+
+// relationshipNames returns the sorted names of the resource's registered and
+// declared relationships
+func (res *Resource) relationshipNames() []string {
+	names := []string{}
+	for name := range res.Relationships {
+		names = append(names, name)
+	}
+
+	if res.Schema != nil {
+		for _, relationship := range res.Schema.Relationships {
+			if _, exists := res.Relationships[relationship.Name]; !exists {
+				names = append(names, relationship.Name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// relationshipSchema looks up a relationship declared by the resource's schema
+func (res *Resource) relationshipSchema(name string) (RelationshipSchema, bool) {
+	if res.Schema == nil {
+		return RelationshipSchema{}, false
+	}
+
+	return res.Schema.FindRelationship(name)
+}
+
+// openAPIAttribute describes the value of an attribute
+func openAPIAttribute(attribute AttributeSchema) map[string]interface{} {
+	schema := map[string]interface{}{"type": string(attribute.Type)}
+	if attribute.Type == AttributeDateTime {
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	if attribute.ReadOnly {
+		schema["readOnly"] = true
+	}
+
+	return schema
+}
+
+// openAPIBaseSchemas returns the component schemas shared by all resources
+func openAPIBaseSchemas() map[string]interface{} {
+	identifier := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"type", "id"},
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{"type": "string"},
+			"id":   map[string]interface{}{"type": "string"},
+			"meta": openAPIRef("schemas", "Meta"),
+		},
+	}
+
+	resource := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"type", "id"},
+		"properties": map[string]interface{}{
+			"type":          map[string]interface{}{"type": "string"},
+			"id":            map[string]interface{}{"type": "string"},
+			"attributes":    map[string]interface{}{"type": "object"},
+			"relationships": map[string]interface{}{"type": "object"},
+			"links":         openAPIRef("schemas", "Links"),
+			"meta":          openAPIRef("schemas", "Meta"),
+		},
+	}
+
+	link := map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":     "object",
+				"required": []string{"href"},
+				"properties": map[string]interface{}{
+					"href": map[string]interface{}{"type": "string"},
+					"meta": openAPIRef("schemas", "Meta"),
+				},
+			},
+		},
+	}
+
+	errorSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "string"},
+			"code":   map[string]interface{}{"type": "string"},
+			"title":  map[string]interface{}{"type": "string"},
+			"detail": map[string]interface{}{"type": "string"},
+			"source": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pointer":   map[string]interface{}{"type": "string"},
+					"parameter": map[string]interface{}{"type": "string"},
+				},
+			},
+			"meta": openAPIRef("schemas", "Meta"),
+		},
+	}
+
+	// action requests and responses may carry any kind of data
+	action := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data":  map[string]interface{}{},
+			"links": openAPIRef("schemas", "Links"),
+			"meta":  openAPIRef("schemas", "Meta"),
+		},
+	}
+
+	return map[string]interface{}{
+		"Meta":               map[string]interface{}{"type": "object"},
+		"Link":               link,
+		"Links":              map[string]interface{}{"type": "object", "additionalProperties": openAPIRef("schemas", "Link")},
+		"ResourceIdentifier": identifier,
+		"ToOneLinkage": openAPILinkageSchema(map[string]interface{}{
+			"oneOf": []interface{}{openAPIRef("schemas", "ResourceIdentifier"), map[string]interface{}{"type": "null"}},
+		}),
+		"ToManyLinkage":        openAPILinkageSchema(openAPIArray(openAPIRef("schemas", "ResourceIdentifier"))),
+		"Resource":             resource,
+		"ResourceDocument":     openAPIDocumentSchema(openAPIRef("schemas", "Resource")),
+		"ResourceListDocument": openAPIDocumentSchema(openAPIArray(openAPIRef("schemas", "Resource"))),
+		"ActionDocument":       action,
+		"Error":                errorSchema,
+		"Errors": map[string]interface{}{
+			"type":       "object",
+			"required":   []string{"errors"},
+			"properties": map[string]interface{}{"errors": openAPIArray(openAPIRef("schemas", "Error"))},
+		},
+	}
+}
+
+// openAPIParameters returns the parameters shared by all resources
+func openAPIParameters() map[string]interface{} {
+	query := func(name string, description string, schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":        name,
+			"in":          "query",
+			"description": description,
+			"schema":      schema,
+		}
+	}
+
+	str := map[string]interface{}{"type": "string"}
+	integer := map[string]interface{}{"type": "integer", "minimum": 1}
+
+	fields := query(fieldsParam, "Sparse fieldsets, keyed by resource type", map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": str,
+	})
+	fields["style"] = "deepObject"
+	fields["explode"] = true
+
+	return map[string]interface{}{
+		"id": map[string]interface{}{
+			"name":     "id",
+			"in":       "path",
+			"required": true,
+			"schema":   str,
+		},
+		"include":    query(jsh.IncludeParam, "Comma separated relationship paths to include", str),
+		"fields":     fields,
+		"sort":       query(sortParam, "Comma separated fields to sort by, prefixed with - to sort descending", str),
+		"pageNumber": query(pageNumberParam, "Page number, starting at 1", integer),
+		"pageSize":   query(pageSizeParam, "Number of resources per page", integer),
+		"pageCursor": query(pageCursorParam, "Opaque cursor of the page to fetch", str),
+		"ifMatch": map[string]interface{}{
+			"name":        "If-Match",
+			"in":          "header",
+			"description": "The ETag of the version being modified",
+			"schema":      str,
+		},
+	}
+}
+
+// openAPIErrorResponses returns an error response for every status generated
+// operations reference
+func openAPIErrorResponses() map[string]interface{} {
+	responses := map[string]interface{}{}
+	for _, status := range []int{
+		http.StatusBadRequest,
+		http.StatusForbidden,
+		http.StatusNotFound,
+		http.StatusConflict,
+		http.StatusPreconditionFailed,
+		http.StatusUnprocessableEntity,
+		http.StatusPreconditionRequired,
+		http.StatusInternalServerError,
+	} {
+		responses[fmt.Sprintf("Error%d", status)] = openAPIDocument(http.StatusText(status), "Errors")
+	}
+
+	return responses
+}
+
+// openAPIDocumentSchema describes a top level document carrying data
+func openAPIDocumentSchema(data interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"data"},
+		"properties": map[string]interface{}{
+			"data":     data,
+			"included": openAPIArray(openAPIRef("schemas", "Resource")),
+			"links":    openAPIRef("schemas", "Links"),
+			"meta":     openAPIRef("schemas", "Meta"),
+		},
+	}
+}
+
+// openAPILinkageSchema describes a relationship document carrying linkage
+func openAPILinkageSchema(data interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"data"},
+		"properties": map[string]interface{}{
+			"data":  data,
+			"links": openAPIRef("schemas", "Links"),
+			"meta":  openAPIRef("schemas", "Meta"),
+		},
+	}
+}
+
+// openAPIDocument describes a response carrying a JSON API document
+func openAPIDocument(description string, schema string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			jsh.ContentType: map[string]interface{}{"schema": openAPIRef("schemas", schema)},
+		},
+	}
+}
+
+// openAPIRequest describes a request body carrying a JSON API document
+func openAPIRequest(schema string) map[string]interface{} {
+	return map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			jsh.ContentType: map[string]interface{}{"schema": openAPIRef("schemas", schema)},
+		},
+	}
+}
+
+// openAPIRef references a component
+func openAPIRef(kind string, name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": fmt.Sprintf("#/components/%s/%s", kind, name)}
+}
+
+// openAPIArray describes an array of items
+func openAPIArray(items interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+// openAPIList returns the parameters already added to an operation
+func openAPIList(parameters interface{}) []interface{} {
+	list, _ := parameters.([]interface{})
+	return list
+}
+
+// openAPIVerb describes what a method does to a relationship's linkage
+func openAPIVerb(method string) string {
+	switch method {
+	case post:
+		return "Add to"
+	case patch:
+		return "Replace"
+	case delete:
+		return "Remove from"
+	default:
+		return "Get"
+	}
+}
+
+// openAPIName converts a resource type into a component name, e.g. "blog-posts"
+// becomes "Blog-posts"
+func openAPIName(resourceType string) string {
+	name := openAPIComponent.ReplaceAllString(resourceType, "_")
+	if name == "" {
+		return name
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
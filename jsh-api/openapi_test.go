@@ -0,0 +1,156 @@
+package jshapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store/memory"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOpenAPI(t *testing.T) {
+
+	Convey("OpenAPI Tests", t, func() {
+
+		articles := NewCRUDResource("articles", memory.New("articles"))
+		articles.SetSchema((&Schema{}).
+			Attribute(AttributeSchema{Name: "title", Type: AttributeString, Required: true, Filterable: true}).
+			Attribute(AttributeSchema{Name: "created", Type: AttributeDateTime, ReadOnly: true}).
+			ToOne("author", "people"))
+		articles.ToOne("author", func(ctx context.Context, id string) (*jsh.Object, error) {
+			return nil, nil
+		})
+		articles.ToMany("comments", func(ctx context.Context, id string) (jsh.List, error) {
+			return nil, nil
+		})
+		articles.Action("publish", func(ctx context.Context, id string) (*jsh.Object, error) {
+			return nil, nil
+		})
+
+		people := NewResource("people")
+		people.Get(func(ctx context.Context, id string) (*jsh.Object, error) {
+			return nil, nil
+		})
+		people.List(func(ctx context.Context) (jsh.List, error) {
+			return nil, nil
+		})
+
+		api := New("api")
+		api.Title = "Blog"
+		api.Add(articles)
+		api.Add(people)
+
+		// round trip through JSON to compare against plain values
+		content, err := json.Marshal(api.OpenAPI())
+		So(err, ShouldBeNil)
+
+		var doc map[string]interface{}
+		So(json.Unmarshal(content, &doc), ShouldBeNil)
+
+		paths := doc["paths"].(map[string]interface{})
+		schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+		operation := func(path string, method string) map[string]interface{} {
+			operations, exists := paths[path].(map[string]interface{})
+			So(exists, ShouldBeTrue)
+
+			operation, exists := operations[method].(map[string]interface{})
+			So(exists, ShouldBeTrue)
+			return operation
+		}
+
+		responseSchema := func(operation map[string]interface{}, status string) string {
+			response := operation["responses"].(map[string]interface{})[status].(map[string]interface{})
+			content := response["content"].(map[string]interface{})[jsh.ContentType].(map[string]interface{})
+			return content["schema"].(map[string]interface{})["$ref"].(string)
+		}
+
+		parameters := func(operation map[string]interface{}) []interface{} {
+			names := []interface{}{}
+			for _, parameter := range operation["parameters"].([]interface{}) {
+				parameter := parameter.(map[string]interface{})
+				if ref, isRef := parameter["$ref"]; isRef {
+					names = append(names, ref)
+				} else {
+					names = append(names, parameter["name"])
+				}
+			}
+			return names
+		}
+
+		Convey("should describe the API", func() {
+			So(doc["openapi"], ShouldEqual, OpenAPIVersion)
+			So(doc["info"].(map[string]interface{})["title"], ShouldEqual, "Blog")
+			So(len(paths), ShouldEqual, 9)
+		})
+
+		Convey("should document CRUD operations with JSON API documents", func() {
+			list := operation("/api/articles", "get")
+			So(list["operationId"], ShouldEqual, "articles.list")
+			So(responseSchema(list, "200"), ShouldEqual, "#/components/schemas/ArticlesListDocument")
+
+			create := operation("/api/articles", "post")
+			So(responseSchema(create, "201"), ShouldEqual, "#/components/schemas/ArticlesDocument")
+			So(create["responses"], ShouldContainKey, "422")
+
+			remove := operation("/api/articles/{id}", "delete")
+			So(remove["responses"], ShouldContainKey, "204")
+
+			update := operation("/api/articles/{id}", "patch")
+			So(update["operationId"], ShouldEqual, "articles.update")
+		})
+
+		Convey("should only document query parameters storage supports", func() {
+			So(parameters(operation("/api/articles", "get")), ShouldResemble, []interface{}{
+				"#/components/parameters/fields",
+				"#/components/parameters/sort",
+				"filter",
+				"#/components/parameters/pageNumber",
+				"#/components/parameters/pageSize",
+				"#/components/parameters/pageCursor",
+				"#/components/parameters/include",
+			})
+
+			So(parameters(operation("/api/people", "get")), ShouldResemble, []interface{}{
+				"#/components/parameters/fields",
+				"#/components/parameters/include",
+			})
+		})
+
+		Convey("should document relationship and action routes", func() {
+			So(responseSchema(operation("/api/articles/{id}/author", "get"), "200"), ShouldEqual, "#/components/schemas/PeopleDocument")
+			So(responseSchema(operation("/api/articles/{id}/comments", "get"), "200"), ShouldEqual, "#/components/schemas/ResourceListDocument")
+			So(responseSchema(operation("/api/articles/{id}/relationships/comments", "get"), "200"), ShouldEqual, "#/components/schemas/ToManyLinkage")
+			So(responseSchema(operation("/api/articles/{id}/publish", "get"), "200"), ShouldEqual, "#/components/schemas/ResourceDocument")
+		})
+
+		Convey("should build attribute schemas from resource schemas", func() {
+			attributes := schemas["ArticlesAttributes"].(map[string]interface{})
+			So(attributes["required"], ShouldResemble, []interface{}{"title"})
+
+			created := attributes["properties"].(map[string]interface{})["created"]
+			So(created, ShouldResemble, map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true})
+		})
+
+		Convey("should serve the document", func() {
+			api.ServeOpenAPI("openapi.json")
+
+			server := httptest.NewServer(api)
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + "/api/openapi.json")
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+			var served map[string]interface{}
+			So(json.NewDecoder(resp.Body).Decode(&served), ShouldBeNil)
+			So(served["openapi"], ShouldEqual, OpenAPIVersion)
+		})
+	})
+}
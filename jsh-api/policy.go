@@ -0,0 +1,233 @@
+package jshapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+// Action identifies what a request does to a resource, see Policy
+type Action string
+
+// Actions that a Policy is consulted for
+const (
+	ActionList               Action = "list"
+	ActionGet                Action = "get"
+	ActionCreate             Action = "create"
+	ActionUpdate             Action = "update"
+	ActionDelete             Action = "delete"
+	ActionGetRelationship    Action = "get_relationship"
+	ActionUpdateRelationship Action = "update_relationship"
+	ActionCustom             Action = "custom"
+)
+
+// Decision is the outcome of a Policy check, the zero value is Undecided
+type Decision int
+
+const (
+	// Undecided is returned when a Policy doesn't make a decision, the request
+	// is rejected like with Deny
+	Undecided Decision = iota
+	// Allow lets the request through
+	Allow
+	// Deny rejects the request with a 403 Forbidden
+	Deny
+	// Hide rejects the request with a 404 Not Found, concealing that the
+	// resource exists
+	Hide
+)
+
+/*
+PolicyRequest describes the request a Policy decides on. ID is empty for lists
+and creates, Relationship is set for relationship routes and included
+relationships, Name is the name of a custom action. Object is the parsed request
+object for creates and updates.
+*/
+type PolicyRequest struct {
+	Action       Action
+	Type         string
+	ID           string
+	Relationship string
+	Name         string
+	Object       *jsh.Object
+}
+
+/*
+Policy authorizes the requests made to a resource, it is consulted before storage
+is called. Returning an error aborts the request like a storage error would.
+Related resources, whether fetched via relationship routes or included, are
+checked against the Policy of the resource serving their type with ActionGet,
+and left out of the response when not allowed.
+Who is calling is up to the application, usually something an authentication
+middleware stored in the context:
+
+	resource.Policy = jshapi.PolicyFunc(func(ctx context.Context, request *jshapi.PolicyRequest) (jshapi.Decision, error) {
+		if request.Action != jshapi.ActionGet && !isAdmin(ctx) {
+			return jshapi.Deny, nil
+		}
+		return jshapi.Allow, nil
+	})
+*/
+type Policy interface {
+	Authorize(ctx context.Context, request *PolicyRequest) (Decision, error)
+}
+
+/*
+ScopedPolicy is a Policy that also restricts which resources a caller can list,
+i.e. to those of their tenant. The scope is passed to ListWithQuery storage via
+store.Query.Scope, which storage must apply in addition to the client's filters.
+*/
+type ScopedPolicy interface {
+	Policy
+	Scope(ctx context.Context, resourceType string) (map[string]string, error)
+}
+
+// PolicyFunc allows a plain function to be used as a Policy
+type PolicyFunc func(ctx context.Context, request *PolicyRequest) (Decision, error)
+
+// Authorize calls the function
+func (f PolicyFunc) Authorize(ctx context.Context, request *PolicyRequest) (Decision, error) {
+	return f(ctx, request)
+}
+
+// authorize consults the resource's Policy, if set, and converts the decision
+// into an error
+func (res *Resource) authorize(r *http.Request, request *PolicyRequest) jsh.ErrorType {
+	return res.checkPolicy(r.Context(), r.URL.Path, request)
+}
+
+// checkPolicy works like authorize outside of the request's handler, path is
+// reported for hidden requests without an ID
+func (res *Resource) checkPolicy(ctx context.Context, path string, request *PolicyRequest) jsh.ErrorType {
+	decision, err := res.decide(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	switch decision {
+	case Allow:
+		return nil
+	case Hide:
+		if request.ID == "" {
+			return jsh.RouteNotFound(path)
+		}
+		return jsh.NotFound(res.Type, request.ID)
+	}
+
+	return jsh.Forbidden(fmt.Sprintf("Not allowed to %s '%s'", request.Action, res.Type))
+}
+
+// decide consults the resource's Policy, requests are allowed if none is set
+func (res *Resource) decide(ctx context.Context, request *PolicyRequest) (Decision, jsh.ErrorType) {
+	if res.Policy == nil {
+		return Allow, nil
+	}
+
+	request.Type = res.Type
+
+	decision, policyErr := res.Policy.Authorize(ctx, request)
+	if err := jsh.MapError(policyErr); err != nil {
+		return Undecided, err
+	}
+
+	return decision, nil
+}
+
+/*
+authorizeRelated checks that the Policy of the resource serving a related
+object's type allows getting it, returning the error its decision results in.
+Objects of types that the API doesn't serve are always allowed.
+*/
+func (a *API) authorizeRelated(ctx context.Context, object *jsh.Object) jsh.ErrorType {
+	if a == nil {
+		return nil
+	}
+
+	resource, served := a.Resources[object.Type]
+	if !served {
+		return nil
+	}
+
+	return resource.checkPolicy(ctx, "", &PolicyRequest{Action: ActionGet, ID: object.ID})
+}
+
+/*
+hiddenRelated returns the keys, see includedKey, of the related objects that the
+Policy of the resource serving their type doesn't allow getting. Each distinct
+object is only checked once.
+*/
+func (a *API) hiddenRelated(ctx context.Context, objects jsh.List) (map[string]bool, jsh.ErrorType) {
+	hidden := map[string]bool{}
+	if a == nil {
+		return hidden, nil
+	}
+
+	checked := map[string]bool{}
+	for _, object := range objects {
+		key := includedKey(object)
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+
+		resource, served := a.Resources[object.Type]
+		if !served {
+			continue
+		}
+
+		decision, err := resource.decide(ctx, &PolicyRequest{Action: ActionGet, ID: object.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		if decision != Allow {
+			hidden[key] = true
+		}
+	}
+
+	return hidden, nil
+}
+
+// visibleRelated drops the related objects that may not be gotten, see
+// hiddenRelated
+func (a *API) visibleRelated(ctx context.Context, objects jsh.List) (jsh.List, jsh.ErrorType) {
+	hidden, err := a.hiddenRelated(ctx, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	return withoutHidden(objects, hidden), nil
+}
+
+// withoutHidden returns the objects whose keys aren't hidden
+func withoutHidden(objects jsh.List, hidden map[string]bool) jsh.List {
+	if len(hidden) == 0 {
+		return objects
+	}
+
+	visible := jsh.List{}
+	for _, object := range objects {
+		if !hidden[includedKey(object)] {
+			visible = append(visible, object)
+		}
+	}
+
+	return visible
+}
+
+// scope returns the list scope of a ScopedPolicy, nil otherwise
+func (res *Resource) scope(r *http.Request) (map[string]string, jsh.ErrorType) {
+	scoped, isScoped := res.Policy.(ScopedPolicy)
+	if !isScoped {
+		return nil, nil
+	}
+
+	scope, scopeErr := scoped.Scope(r.Context(), res.Type)
+	if err := jsh.MapError(scopeErr); err != nil {
+		return nil, err
+	}
+
+	return scope, nil
+}
@@ -0,0 +1,203 @@
+package jshapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store/memory"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type tenantKey struct{}
+
+// tenantPolicy lets tenants read, and only list, their own projects
+type tenantPolicy struct {
+	requests []*PolicyRequest
+}
+
+func (p *tenantPolicy) Authorize(ctx context.Context, request *PolicyRequest) (Decision, error) {
+	p.requests = append(p.requests, request)
+
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	switch {
+	case tenant == "":
+		return Deny, nil
+	case request.Action == ActionGet && request.ID != tenant:
+		return Hide, nil
+	case request.Action == ActionDelete:
+		return Deny, nil
+	}
+
+	return Allow, nil
+}
+
+func (p *tenantPolicy) Scope(ctx context.Context, resourceType string) (map[string]string, error) {
+	return map[string]string{"tenant": ctx.Value(tenantKey{}).(string)}, nil
+}
+
+func TestPolicy(t *testing.T) {
+
+	projects := memory.New("projects")
+	for _, tenant := range []string{"1", "2"} {
+		object, _ := jsh.NewObject(tenant, "projects", map[string]string{"tenant": tenant})
+		projects.Save(context.Background(), object)
+	}
+
+	policy := &tenantPolicy{}
+
+	resource := NewCRUDResource("projects", projects)
+	resource.Policy = policy
+
+	api := New("")
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), tenantKey{}, r.Header.Get("X-Tenant"))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	do := func(method string, path string, tenant string) (*jsh.Document, *http.Response) {
+		request, err := jsc.NewRequest(method, server.URL+path, nil)
+		So(err, ShouldBeNil)
+		request.Header.Set("X-Tenant", tenant)
+
+		doc, resp, err := jsc.Do(request, jsh.ListMode)
+		So(err, ShouldBeNil)
+
+		return doc, resp
+	}
+
+	Convey("Policy Tests", t, func() {
+
+		Reset(func() {
+			policy.requests = nil
+		})
+
+		Convey("should allow permitted requests", func() {
+			_, resp := do("GET", "/projects/1", "1")
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(policy.requests[0], ShouldResemble, &PolicyRequest{Action: ActionGet, Type: "projects", ID: "1"})
+		})
+
+		Convey("should deny requests with a 403", func() {
+			doc, resp := do("GET", "/projects/1", "")
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+			So(doc.Errors[0].Code, ShouldEqual, jsh.CodeForbidden)
+
+			_, resp = do("DELETE", "/projects/1", "1")
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("should hide resources with a 404", func() {
+			_, resp := do("GET", "/projects/2", "1")
+			So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("should scope lists", func() {
+			doc, resp := do("GET", "/projects", "2")
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Data), ShouldEqual, 1)
+			So(doc.Data[0].ID, ShouldEqual, "2")
+		})
+	})
+}
+
+func TestRelatedPolicy(t *testing.T) {
+
+	articles := NewResource("articles")
+	articles.Get(func(ctx context.Context, id string) (*jsh.Object, error) {
+		return sampleObject(id, "articles", map[string]string{"title": "JSON API"}), nil
+	})
+	articles.ToOne("author", func(ctx context.Context, id string) (*jsh.Object, error) {
+		return sampleObject("9", "people", map[string]string{"name": "Dan"}), nil
+	})
+	articles.ToMany("comments", func(ctx context.Context, id string) (jsh.List, error) {
+		return jsh.List{
+			sampleObject("1", "comments", map[string]string{"body": "Public"}),
+			sampleObject("2", "comments", map[string]string{"body": "Private"}),
+		}, nil
+	})
+	articles.Policy = PolicyFunc(func(ctx context.Context, request *PolicyRequest) (Decision, error) {
+		return Allow, nil
+	})
+
+	people := NewResource("people")
+	people.Policy = PolicyFunc(func(ctx context.Context, request *PolicyRequest) (Decision, error) {
+		return Deny, nil
+	})
+
+	comments := NewResource("comments")
+	comments.Policy = PolicyFunc(func(ctx context.Context, request *PolicyRequest) (Decision, error) {
+		if request.ID == "1" {
+			return Allow, nil
+		}
+
+		// the zero value rejects the request
+		var decision Decision
+		return decision, nil
+	})
+
+	api := New("")
+	api.Add(articles)
+	api.Add(people)
+	api.Add(comments)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	fetch := func(path string, mode jsh.DocumentMode) (*jsh.Document, *http.Response) {
+		request, err := jsc.NewRequest("GET", server.URL+path, nil)
+		So(err, ShouldBeNil)
+
+		doc, resp, err := jsc.Do(request, mode)
+		So(err, ShouldBeNil)
+
+		return doc, resp
+	}
+
+	Convey("Related Policy Tests", t, func() {
+
+		Convey("should not include related resources that are denied", func() {
+			doc, resp := fetch("/articles/1?include=author,comments", jsh.ObjectMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+			article := doc.First()
+			So(article.Relationships["author"].Data, ShouldBeEmpty)
+			So(len(article.Relationships["comments"].Data), ShouldEqual, 1)
+			So(article.Relationships["comments"].Data[0].ID, ShouldEqual, "1")
+
+			So(len(doc.Included), ShouldEqual, 1)
+			So(doc.Included[0].Type, ShouldEqual, "comments")
+			So(doc.Included[0].ID, ShouldEqual, "1")
+		})
+
+		Convey("should deny a related resource", func() {
+			doc, resp := fetch("/articles/1/author", jsh.ObjectMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+			So(doc.Errors[0].Code, ShouldEqual, jsh.CodeForbidden)
+
+			doc, resp = fetch("/articles/1/relationships/author", jsh.LinkageMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.Linkage.Data, ShouldBeEmpty)
+		})
+
+		Convey("should drop denied resources from related lists", func() {
+			doc, resp := fetch("/articles/1/comments", jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Data), ShouldEqual, 1)
+			So(doc.Data[0].ID, ShouldEqual, "1")
+
+			doc, resp = fetch("/articles/1/relationships/comments", jsh.LinkageMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Linkage.Data), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,384 @@
+package jshapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+// query parameters parsed into a store.Query
+const (
+	sortParam       = "sort"
+	fieldsParam     = "fields"
+	filterParam     = "filter"
+	pageParam       = "page"
+	pageNumberParam = "page[number]"
+	pageSizeParam   = "page[size]"
+	pageCursorParam = "page[cursor]"
+)
+
+// GET /resources, using storage that is passed the request's query
+func (res *Resource) listQueryHandler(w http.ResponseWriter, r *http.Request, storage store.ListWithQuery) {
+	query, queryErr := res.parseQuery(r)
+	if queryErr != nil {
+		res.send(w, r, queryErr)
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionList}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	scope, scopeErr := res.scope(r)
+	if scopeErr != nil {
+		res.send(w, r, scopeErr)
+		return
+	}
+	query.Scope = scope
+
+	result, storageErr := storage(r.Context(), query)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if result == nil {
+		result = &store.ListResult{}
+	}
+
+	list := result.Objects
+	if list == nil {
+		list = jsh.List{}
+	}
+
+	if err := runHooks(r.Context(), res.hooks.afterFetch, list...); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	document := apiFromRequest(r).config().Build(list)
+	document.Status = http.StatusOK
+	document.Links = paginationLinks(r.URL, query.Page, result)
+
+	if result.TotalKnown {
+		document.Meta = map[string]interface{}{"total": result.Total}
+	}
+
+	res.sendIncluded(w, r, document)
+}
+
+/*
+parseQuery builds the store.Query for a list request, responding with a 400 to
+malformed parameters as well as sort, filter and sparse fieldset fields that the
+resource, or its schema, doesn't support.
+*/
+func (res *Resource) parseQuery(r *http.Request) (*store.Query, *jsh.Error) {
+	include, err := jsh.ParseInclude(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := res.parseFields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &store.Query{
+		Include: include,
+		Fields:  fields,
+		Sort:    []store.SortField{},
+		Filter:  map[string]string{},
+		Page:    store.Page{Size: res.DefaultPageSize},
+	}
+
+	params := r.URL.Query()
+	for _, param := range sortedParams(params) {
+		value := params.Get(param)
+
+		switch {
+		case param == sortParam:
+			err = res.parseSort(r.Context(), query, value)
+		case strings.HasPrefix(param, filterParam+"["):
+			name, valid := bracketed(param, filterParam)
+			if !valid {
+				err = invalidParameter(param)
+				break
+			}
+			if !res.filterable(name) || !res.attributeVisible(r.Context(), name) {
+				err = jsh.ParameterError(fmt.Sprintf("Filtering by '%s' is not supported", name), param)
+				break
+			}
+			query.Filter[name] = value
+		case strings.HasPrefix(param, pageParam+"["):
+			err = res.parsePage(query, param, value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if query.Page.Size > 0 && query.Page.Cursor == "" && query.Page.Number == 0 {
+		query.Page.Number = 1
+	}
+
+	return query, nil
+}
+
+// parseSort parses a comma separated list of fields, descending fields are
+// prefixed with "-"
+func (res *Resource) parseSort(ctx context.Context, query *store.Query, value string) *jsh.Error {
+	for _, field := range strings.Split(value, ",") {
+		sortField := store.SortField{Field: field}
+
+		if strings.HasPrefix(field, "-") {
+			sortField.Field = strings.TrimPrefix(field, "-")
+			sortField.Descending = true
+		}
+
+		if sortField.Field == "" {
+			return jsh.ParameterError(fmt.Sprintf("Invalid sort field '%s'", field), sortParam)
+		}
+
+		if !res.sortable(sortField.Field) || !res.attributeVisible(ctx, sortField.Field) {
+			return jsh.ParameterError(
+				fmt.Sprintf("Sorting by '%s' is not supported", sortField.Field),
+				sortParam,
+			)
+		}
+
+		query.Sort = append(query.Sort, sortField)
+	}
+
+	return nil
+}
+
+// parsePage parses a "page[number]", "page[size]" or "page[cursor]" parameter
+func (res *Resource) parsePage(query *store.Query, param string, value string) *jsh.Error {
+	if param == pageCursorParam {
+		query.Page.Cursor = value
+		return nil
+	}
+
+	if param != pageNumberParam && param != pageSizeParam {
+		return invalidParameter(param)
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return jsh.ParameterError(fmt.Sprintf("'%s' must be a positive integer", param), param)
+	}
+
+	if param == pageNumberParam {
+		query.Page.Number = number
+		return nil
+	}
+
+	if res.MaxPageSize > 0 && number > res.MaxPageSize {
+		return jsh.ParameterError(
+			fmt.Sprintf("'%s' must not be greater than %d", param, res.MaxPageSize),
+			param,
+		)
+	}
+
+	query.Page.Size = number
+	return nil
+}
+
+/*
+paginationLinks builds the self and pagination links of a list. Cursors returned
+by storage take precedence, otherwise page numbers are used. The last link is
+only set when the total is known.
+*/
+func paginationLinks(requestURL *url.URL, page store.Page, result *store.ListResult) *jsh.Links {
+	link := func(params map[string]string) *jsh.Link {
+		values := requestURL.Query()
+		for param, value := range params {
+			values.Del(param)
+			if value != "" {
+				values.Set(param, value)
+			}
+		}
+
+		linkURL := url.URL{Path: requestURL.Path, RawQuery: values.Encode()}
+		return jsh.NewLink(linkURL.String())
+	}
+
+	links := &jsh.Links{Self: link(nil)}
+	if page.Size == 0 {
+		return links
+	}
+
+	size := strconv.Itoa(page.Size)
+	numbered := func(number int) *jsh.Link {
+		return link(map[string]string{
+			pageNumberParam: strconv.Itoa(number),
+			pageSizeParam:   size,
+			pageCursorParam: "",
+		})
+	}
+	cursor := func(cursor string) *jsh.Link {
+		return link(map[string]string{
+			pageNumberParam: "",
+			pageSizeParam:   size,
+			pageCursorParam: cursor,
+		})
+	}
+
+	if page.Cursor != "" || result.NextCursor != "" || result.PrevCursor != "" {
+		links.First = cursor("")
+		if result.PrevCursor != "" {
+			links.Prev = cursor(result.PrevCursor)
+		}
+		if result.NextCursor != "" {
+			links.Next = cursor(result.NextCursor)
+		}
+		return links
+	}
+
+	links.First = numbered(1)
+	if page.Number > 1 {
+		links.Prev = numbered(page.Number - 1)
+	}
+
+	if result.TotalKnown {
+		// an empty result still has a first, and last, page
+		last := (result.Total + page.Size - 1) / page.Size
+		if last < 1 {
+			last = 1
+		}
+		links.Last = numbered(last)
+
+		if page.Number < last {
+			links.Next = numbered(page.Number + 1)
+		}
+	} else if len(result.Objects) == page.Size {
+		links.Next = numbered(page.Number + 1)
+	}
+
+	return links
+}
+
+/*
+parseFields parses the "fields[type]" sparse fieldset parameters of a request,
+responding with a 400 to fields that aren't part of the schema of the resource
+serving each type.
+*/
+func (res *Resource) parseFields(r *http.Request) (map[string][]string, *jsh.Error) {
+	fields := map[string][]string{}
+
+	params := r.URL.Query()
+	for _, param := range sortedParams(params) {
+		if !strings.HasPrefix(param, fieldsParam+"[") {
+			continue
+		}
+
+		resourceType, valid := bracketed(param, fieldsParam)
+		if !valid {
+			return nil, invalidParameter(param)
+		}
+
+		fields[resourceType] = splitFields(params.Get(param))
+
+		err := checkFields(apiFromRequest(r), res, resourceType, fields[resourceType], param)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+// checkFields rejects sparse fieldsets naming fields that aren't part of the
+// schema of the resource serving resourceType
+func checkFields(api *API, res *Resource, resourceType string, fields []string, param string) *jsh.Error {
+	resource := res
+	if resourceType != res.Type {
+		if api == nil {
+			return nil
+		}
+
+		resource = api.Resources[resourceType]
+		if resource == nil {
+			return nil
+		}
+	}
+
+	if resource.Schema == nil {
+		return nil
+	}
+
+	for _, field := range fields {
+		if !resource.Schema.hasField(field) {
+			return jsh.ParameterError(
+				fmt.Sprintf("Resource '%s' has no field '%s'", resourceType, field),
+				param,
+			)
+		}
+	}
+
+	return nil
+}
+
+// bracketed returns the name within a "param[name]" query parameter
+func bracketed(param string, prefix string) (string, bool) {
+	name := strings.TrimPrefix(param, prefix+"[")
+	if !strings.HasSuffix(name, "]") {
+		return "", false
+	}
+
+	name = strings.TrimSuffix(name, "]")
+	return name, name != "" && !strings.ContainsAny(name, "[]")
+}
+
+// sortedParams returns the names of the query parameters in order, so that the
+// same request always reports the same invalid parameter
+func sortedParams(params url.Values) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// splitFields splits a comma separated list of fields, ignoring empty ones
+func splitFields(value string) []string {
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// allowed checks whether a field is within a list of supported fields, all fields
+// are supported if the list is empty
+func allowed(supported []string, field string) bool {
+	if len(supported) == 0 {
+		return true
+	}
+
+	for _, candidate := range supported {
+		if candidate == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func invalidParameter(param string) *jsh.Error {
+	return jsh.ParameterError(fmt.Sprintf("Invalid query parameter '%s'", param), param)
+}
@@ -0,0 +1,215 @@
+package jshapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseQuery(t *testing.T) {
+
+	Convey("Parse Query Tests", t, func() {
+
+		resource := NewResource("articles")
+		resource.SortFields = []string{"title", "created"}
+		resource.FilterFields = []string{"author"}
+		resource.MaxPageSize = 50
+
+		parse := func(rawQuery string) (*store.Query, *jsh.Error) {
+			request := httptest.NewRequest("GET", "/articles?"+rawQuery, nil)
+			return resource.parseQuery(request)
+		}
+
+		Convey("should parse all query parameters", func() {
+			query, err := parse("include=author&fields[articles]=title,body&sort=-created,title&filter[author]=9&page[number]=2&page[size]=10")
+			So(err, ShouldBeNil)
+
+			So(query.Include, ShouldContainKey, "author")
+			So(query.Fields, ShouldResemble, map[string][]string{"articles": {"title", "body"}})
+			So(query.Sort, ShouldResemble, []store.SortField{
+				{Field: "created", Descending: true},
+				{Field: "title"},
+			})
+			So(query.Filter, ShouldResemble, map[string]string{"author": "9"})
+			So(query.Page, ShouldResemble, store.Page{Number: 2, Size: 10})
+		})
+
+		Convey("should default the page size", func() {
+			resource.DefaultPageSize = 20
+
+			query, err := parse("")
+			So(err, ShouldBeNil)
+			So(query.Page, ShouldResemble, store.Page{Number: 1, Size: 20})
+		})
+
+		Convey("should reject invalid parameters", func() {
+			invalid := map[string]string{
+				"sort=body":           sortParam,
+				"sort=title,":         sortParam,
+				"filter[editor]=1":    "filter[editor]",
+				"fields[]=title":      "fields[]",
+				"page[number]=0":      pageNumberParam,
+				"page[size]=abc":      pageSizeParam,
+				"page[size]=51":       pageSizeParam,
+				"page[offset]=10":     "page[offset]",
+				"include=author..bio": jsh.IncludeParam,
+			}
+
+			for rawQuery, parameter := range invalid {
+				_, err := parse(rawQuery)
+				So(err, ShouldNotBeNil)
+				So(err.Status, ShouldEqual, http.StatusBadRequest)
+				So(err.Source.Parameter, ShouldEqual, parameter)
+			}
+		})
+
+		Convey("should always report the same of several invalid parameters", func() {
+			for i := 0; i < 20; i++ {
+				_, err := parse("sort=body&page[size]=abc&filter[editor]=1&fields[]=title")
+				So(err.Source.Parameter, ShouldEqual, "fields[]")
+
+				_, err = parse("sort=body&page[size]=abc&filter[editor]=1")
+				So(err.Source.Parameter, ShouldEqual, "filter[editor]")
+			}
+		})
+	})
+}
+
+func TestListWithQuery(t *testing.T) {
+
+	var lastQuery *store.Query
+
+	articles := NewResource("articles")
+	articles.ListQuery(func(ctx context.Context, query *store.Query) (*store.ListResult, error) {
+		lastQuery = query
+
+		list := jsh.List{}
+		for i := 0; i < query.Page.Size; i++ {
+			id := strconv.Itoa((query.Page.Number-1)*query.Page.Size + i + 1)
+			list = append(list, sampleObject(id, "articles", map[string]string{"title": "JSON API"}))
+		}
+
+		return &store.ListResult{Objects: list, Total: 25, TotalKnown: true}, nil
+	})
+
+	comments := NewResource("comments")
+	comments.List(func(ctx context.Context) (jsh.List, error) {
+		return jsh.List{}, nil
+	})
+
+	api := New("")
+	api.Add(articles)
+	api.Add(comments)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	fetch := func(path string) (*jsh.Document, *http.Response) {
+		request, err := jsc.NewRequest("GET", server.URL+path, nil)
+		So(err, ShouldBeNil)
+
+		doc, resp, err := jsc.Do(request, jsh.ListMode)
+		So(err, ShouldBeNil)
+
+		return doc, resp
+	}
+
+	linkQuery := func(link *jsh.Link) url.Values {
+		So(link, ShouldNotBeNil)
+
+		parsed, err := url.Parse(link.HREF)
+		So(err, ShouldBeNil)
+		So(parsed.Path, ShouldEqual, "/articles")
+
+		return parsed.Query()
+	}
+
+	Convey("List With Query Tests", t, func() {
+
+		Convey("should pass the query to storage", func() {
+			doc, resp := fetch("/articles?sort=-title&page[number]=2&page[size]=10")
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Data), ShouldEqual, 10)
+			So(doc.Data[0].ID, ShouldEqual, "11")
+
+			So(lastQuery.Sort, ShouldResemble, []store.SortField{{Field: "title", Descending: true}})
+			So(lastQuery.Page, ShouldResemble, store.Page{Number: 2, Size: 10})
+		})
+
+		Convey("should generate pagination links", func() {
+			doc, _ := fetch("/articles?sort=-title&page[number]=2&page[size]=10")
+
+			So(linkQuery(doc.Links.First).Get(pageNumberParam), ShouldEqual, "1")
+			So(linkQuery(doc.Links.Prev).Get(pageNumberParam), ShouldEqual, "1")
+			So(linkQuery(doc.Links.Next).Get(pageNumberParam), ShouldEqual, "3")
+			So(linkQuery(doc.Links.Last).Get(pageNumberParam), ShouldEqual, "3")
+			So(linkQuery(doc.Links.Next).Get(sortParam), ShouldEqual, "-title")
+			So(linkQuery(doc.Links.Next).Get(pageSizeParam), ShouldEqual, "10")
+
+			So(doc.Meta.(map[string]interface{})["total"], ShouldEqual, 25)
+		})
+
+		Convey("should omit the next link on the last page", func() {
+			doc, _ := fetch("/articles?page[number]=3&page[size]=10")
+			So(doc.Links.Next, ShouldBeNil)
+			So(linkQuery(doc.Links.Prev).Get(pageNumberParam), ShouldEqual, "2")
+		})
+
+		Convey("should reject sorting for storage without queries", func() {
+			request, err := jsc.NewRequest("GET", server.URL+"/comments?sort=body", nil)
+			So(err, ShouldBeNil)
+
+			doc, resp, err := jsc.Do(request, jsh.ListMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(doc.Errors[0].Source.Parameter, ShouldEqual, sortParam)
+		})
+	})
+}
+
+func TestPaginationLinks(t *testing.T) {
+
+	Convey("Pagination Link Tests", t, func() {
+
+		requestURL, _ := url.Parse("/articles?page[cursor]=b&page[size]=2")
+
+		Convey("should prefer storage cursors", func() {
+			links := paginationLinks(
+				requestURL,
+				store.Page{Size: 2, Cursor: "b"},
+				&store.ListResult{NextCursor: "c", PrevCursor: "a"},
+			)
+
+			next, _ := url.Parse(links.Next.HREF)
+			So(next.Query().Get(pageCursorParam), ShouldEqual, "c")
+			So(next.Query().Get(pageSizeParam), ShouldEqual, "2")
+
+			first, _ := url.Parse(links.First.HREF)
+			So(first.Query().Get(pageCursorParam), ShouldEqual, "")
+			So(links.Last, ShouldBeNil)
+		})
+
+		Convey("should link a single last page for an empty result", func() {
+			links := paginationLinks(requestURL, store.Page{Number: 1, Size: 2}, &store.ListResult{TotalKnown: true})
+
+			last, _ := url.Parse(links.Last.HREF)
+			So(last.Query().Get(pageNumberParam), ShouldEqual, "1")
+			So(links.Next, ShouldBeNil)
+		})
+
+		Convey("should only set the self link without pagination", func() {
+			links := paginationLinks(requestURL, store.Page{}, &store.ListResult{})
+			So(links.Self, ShouldNotBeNil)
+			So(links.First, ShouldBeNil)
+			So(links.Next, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package jshapi
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-stdlogger"
+)
+
+/*
+Recoverer builds a middleware that recovers from panics raised while handling a
+request, such as within a storage function. The panic and its stack trace are
+logged, and a jsh.ISE is sent using the Sender of the API serving the request.
+If the API is in Debug mode, the stack trace is also included in the error's meta.
+
+Recoverer is installed by Default, to add it to your own API:
+
+	api := jshapi.New("")
+	api.Use(jshapi.Recoverer(logger))
+
+The response can't be replaced if the handler already started writing it before
+panicking.
+*/
+func Recoverer(logger std.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				// net/http uses this panic to abort responses on purpose
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := string(debug.Stack())
+				logger.Printf("Recovered from panic: %v\n%s", recovered, stack)
+
+				api := apiFromRequest(r)
+
+				err := jsh.ISE(fmt.Sprintf("Recovered from panic: %v", recovered))
+				if api != nil && api.Debug {
+					err.Meta = map[string]interface{}{"stack": stack}
+				}
+
+				api.sender()(w, r, err)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
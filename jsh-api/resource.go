@@ -1,13 +1,16 @@
 package jshapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
-	"reflect"
+	"sort"
 	"strings"
 
 	"goji.io"
+	"goji.io/middleware"
 	"goji.io/pat"
 
 	"github.com/derekdowling/go-json-spec-handler"
@@ -20,6 +23,9 @@ const (
 	list    = "LIST"
 	delete  = "DELETE"
 	patch   = "PATCH"
+	put     = "PUT"
+	head    = "HEAD"
+	options = "OPTIONS"
 	patID   = "/:id"
 	patRoot = ""
 )
@@ -53,6 +59,38 @@ type Resource struct {
 	Routes []string
 	// Map of relationships
 	Relationships map[string]Relationship
+	// methods tracks the HTTP methods registered to each route pattern
+	methods map[string][]string
+	// routes describes each registered route in registration order
+	routes []Route
+	// toOne and toMany hold the batched storage of each relationship, used to
+	// resolve included resources
+	toOne  map[string]store.GetMany
+	toMany map[string]store.ToManyBatch
+	// get is the storage registered via Get, used to resolve If-Match lists
+	get store.Get
+	// RequireIfMatch rejects versioned PATCH and DELETE requests that don't carry
+	// an If-Match header with a 428 Precondition Required
+	RequireIfMatch bool
+	// DefaultPageSize is the page size used for ListWithQuery storage when the
+	// request doesn't specify one, 0 requests all results
+	DefaultPageSize int
+	// MaxPageSize rejects requests for larger pages with a 400 when set
+	MaxPageSize int
+	// SortFields and FilterFields restrict the fields that lists can be sorted
+	// and filtered by when set
+	SortFields   []string
+	FilterFields []string
+	// hooks are the lifecycle hooks registered via BeforeSave, AfterSave, etc.
+	hooks hooks
+	// Policy authorizes every request made to the resource when set
+	Policy Policy
+	// AttributeRules restrict who can see and write attributes, keyed by name
+	AttributeRules map[string]AttributeRule
+	// Schema declares the resource's attributes and relationships, see SetSchema
+	Schema *Schema
+	// queryList is set when lists are served by ListWithQuery storage
+	queryList bool
 }
 
 /*
@@ -63,15 +101,25 @@ managing routes and handling API calls.
 The prefix parameter causes all routes created within the resource to be prefixed.
 */
 func NewResource(resourceType string) *Resource {
-	return &Resource{
+	resource := &Resource{
 		// Mux is a goji.SubMux, inherits context from parent Mux
 		Mux: goji.SubMux(),
 		// Type of the resource, makes no assumptions about plurality
 		Type:          resourceType,
 		Relationships: map[string]Relationship{},
+		methods:       map[string][]string{},
+		toOne:         map[string]store.GetMany{},
+		toMany:        map[string]store.ToManyBatch{},
+		// no attribute restrictions by default
+		AttributeRules: map[string]AttributeRule{},
 		// A list of registered routes, useful for debugging
 		Routes: []string{},
 	}
+
+	// respond with JSON API errors to requests that no route handles
+	resource.Use(resource.unmatchedMiddleware)
+
+	return resource
 }
 
 // NewCRUDResource generates a resource
@@ -91,79 +139,160 @@ Registers handlers for:
 	GET    /resource/:id
 	DELETE /resource/:id
 	PATCH  /resource/:id
+
+If storage also implements store.VersionedUpdate or store.VersionedDelete, the
+PATCH and DELETE routes honor If-Match preconditions via VersionedPatch and
+VersionedDelete. If it implements store.QueryList, lists are served using
+ListQuery.
 */
 func (res *Resource) CRUD(storage store.CRUD) {
 	res.Get(storage.Get)
-	res.Patch(storage.Update)
 	res.Post(storage.Save)
-	res.List(storage.List)
-	res.Delete(storage.Delete)
+
+	if queryList, isQueryList := storage.(store.QueryList); isQueryList {
+		res.ListQuery(queryList.ListQuery)
+	} else {
+		res.List(storage.List)
+	}
+
+	if versioned, isVersioned := storage.(store.VersionedUpdate); isVersioned {
+		res.VersionedPatch(versioned)
+	} else {
+		res.Patch(storage.Update)
+	}
+
+	if versioned, isVersioned := storage.(store.VersionedDelete); isVersioned {
+		res.VersionedDelete(versioned)
+	} else {
+		res.Delete(storage.Delete)
+	}
 }
 
 // Post registers a `POST /resource` handler with the resource
 func (res *Resource) Post(storage store.Save) {
-	res.HandleFunc(
-		pat.Post(patRoot),
+	res.handle(
+		post,
+		patRoot,
 		func(w http.ResponseWriter, r *http.Request) {
 			res.postHandler(w, r, storage)
 		},
 	)
 
-	res.addRoute(post, patRoot)
+	res.addRoute(Route{Method: post, Pattern: patRoot, Kind: RouteCreate})
 }
 
 // Get registers a `GET /resource/:id` handler for the resource
 func (res *Resource) Get(storage store.Get) {
-	res.HandleFunc(
-		pat.Get(patID),
+	res.get = storage
+
+	res.handle(
+		get,
+		patID,
 		func(w http.ResponseWriter, r *http.Request) {
 			res.getHandler(w, r, storage)
 		},
 	)
 
-	res.addRoute(get, patID)
+	res.addRoute(Route{Method: get, Pattern: patID, Kind: RouteGet})
 }
 
 // List registers a `GET /resource` handler for the resource
 func (res *Resource) List(storage store.List) {
-	res.HandleFunc(
-		pat.Get(patRoot),
+	res.handle(
+		get,
+		patRoot,
 		func(w http.ResponseWriter, r *http.Request) {
 			res.listHandler(w, r, storage)
 		},
 	)
 
-	res.addRoute(get, patRoot)
+	res.addRoute(Route{Method: get, Pattern: patRoot, Kind: RouteList})
+}
+
+/*
+ListQuery registers a `GET /resource` handler for the resource, passing storage
+the parsed sort, filter, page, fields and include parameters of the request:
+
+	resource.ListQuery(func(ctx context.Context, query *store.Query) (*store.ListResult, error) {
+		...
+	})
+*/
+func (res *Resource) ListQuery(storage store.ListWithQuery) {
+	res.handle(
+		get,
+		patRoot,
+		func(w http.ResponseWriter, r *http.Request) {
+			res.listQueryHandler(w, r, storage)
+		},
+	)
+	res.queryList = true
+
+	res.addRoute(Route{Method: get, Pattern: patRoot, Kind: RouteList})
 }
 
 // Delete registers a `DELETE /resource/:id` handler for the resource
 func (res *Resource) Delete(storage store.Delete) {
-	res.HandleFunc(
-		pat.Delete(patID),
+	res.handle(
+		delete,
+		patID,
 		func(w http.ResponseWriter, r *http.Request) {
 			res.deleteHandler(w, r, storage)
 		},
 	)
 
-	res.addRoute(delete, patID)
+	res.addRoute(Route{Method: delete, Pattern: patID, Kind: RouteDelete})
 }
 
 // Patch registers a `PATCH /resource/:id` handler for the resource
 func (res *Resource) Patch(storage store.Update) {
-	res.HandleFunc(
-		pat.Patch(patID),
+	res.handle(
+		patch,
+		patID,
 		func(w http.ResponseWriter, r *http.Request) {
 			res.patchHandler(w, r, storage)
 		},
 	)
 
-	res.addRoute(patch, patID)
+	res.addRoute(Route{Method: patch, Pattern: patID, Kind: RouteUpdate})
+}
+
+/*
+VersionedPatch registers a `PATCH /resource/:id` handler that passes the version
+expected by the request's If-Match header to storage. If storage returns
+store.ErrVersionMismatch, a 412 Precondition Failed is sent. If-Match lists are
+resolved using the storage registered with Get, they are rejected without it.
+*/
+func (res *Resource) VersionedPatch(storage store.VersionedUpdate) {
+	res.handle(
+		patch,
+		patID,
+		func(w http.ResponseWriter, r *http.Request) {
+			res.versionedPatchHandler(w, r, storage)
+		},
+	)
+
+	res.addRoute(Route{Method: patch, Pattern: patID, Kind: RouteUpdate})
 }
 
-// ToOne registers a `GET /resource/:id/(relationships/)<resourceType>` route which
-// returns a "resourceType" in a One-To-One relationship between the parent resource
-// type and "resourceType" as specified here. The "/relationships/" uri component is
-// optional.
+// VersionedDelete registers a `DELETE /resource/:id` handler that honors If-Match,
+// see VersionedPatch
+func (res *Resource) VersionedDelete(storage store.VersionedDelete) {
+	res.handle(
+		delete,
+		patID,
+		func(w http.ResponseWriter, r *http.Request) {
+			res.versionedDeleteHandler(w, r, storage)
+		},
+	)
+
+	res.addRoute(Route{Method: delete, Pattern: patID, Kind: RouteDelete})
+}
+
+// ToOne registers a `GET /resource/:id/<resourceType>` route which returns a
+// "resourceType" in a One-To-One relationship between the parent resource type and
+// "resourceType" as specified here. It also registers the
+// `GET /resource/:id/relationships/<resourceType>` route which returns the resource
+// linkage derived from the same storage function.
 //
 // CRUD actions on a specific relationship "resourceType" object should be performed
 // via it's own top level /<resourceType> jsh-api handler as per JSONAPI specification.
@@ -171,21 +300,40 @@ func (res *Resource) ToOne(
 	resourceType string,
 	storage store.Get,
 ) {
-	resourceType = strings.TrimSuffix(resourceType, "s")
+	resourceType = toOneName(resourceType)
 
 	res.relationshipHandler(
 		resourceType,
 		func(w http.ResponseWriter, r *http.Request) {
-			res.getHandler(w, r, storage)
+			res.toOneHandler(w, r, resourceType, storage)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			res.toOneLinkageHandler(w, r, resourceType, storage)
 		},
 	)
 
 	res.Relationships[resourceType] = ToOne
+
+	if res.toOne == nil {
+		res.toOne = map[string]store.GetMany{}
+	}
+	res.toOne[resourceType] = getManyFromGet(storage)
+}
+
+// BatchToOne registers the same routes as ToOne, using storage that retrieves the
+// related objects of many parent resources at once. When including the
+// relationship for a list of resources, storage is called only once.
+func (res *Resource) BatchToOne(resourceType string, storage store.GetMany) {
+	resourceType = toOneName(resourceType)
+
+	res.ToOne(resourceType, getFromGetMany(storage))
+	res.toOne[resourceType] = storage
 }
 
-// ToMany registers a `GET /resource/:id/(relationships/)<resourceType>s` route which
-// returns a list of "resourceType"s in a One-To-Many relationship with the parent resource.
-// The "/relationships/" uri component is optional.
+// ToMany registers a `GET /resource/:id/<resourceType>s` route which returns a list
+// of "resourceType"s in a One-To-Many relationship with the parent resource. It also
+// registers the `GET /resource/:id/relationships/<resourceType>s` route which returns
+// the resource linkage derived from the same storage function.
 //
 // CRUD actions on a specific relationship "resourceType" object should be performed
 // via it's own top level /<resourceType> jsh-api handler as per JSONAPI specification.
@@ -193,42 +341,150 @@ func (res *Resource) ToMany(
 	resourceType string,
 	storage store.ToMany,
 ) {
-	if !strings.HasSuffix(resourceType, "s") {
-		resourceType = fmt.Sprintf("%ss", resourceType)
-	}
+	resourceType = toManyName(resourceType)
 
 	res.relationshipHandler(
 		resourceType,
 		func(w http.ResponseWriter, r *http.Request) {
-			res.toManyHandler(w, r, storage)
+			res.toManyHandler(w, r, resourceType, storage)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			res.toManyLinkageHandler(w, r, resourceType, storage)
+		},
+	)
+
+	res.Relationships[resourceType] = ToMany
+
+	if res.toMany == nil {
+		res.toMany = map[string]store.ToManyBatch{}
+	}
+	res.toMany[resourceType] = toManyBatchFromToMany(storage)
+}
+
+// BatchToMany registers the same routes as ToMany, using storage that retrieves
+// the related objects of many parent resources at once. When including the
+// relationship for a list of resources, storage is called only once.
+func (res *Resource) BatchToMany(resourceType string, storage store.ToManyBatch) {
+	resourceType = toManyName(resourceType)
+
+	res.ToMany(resourceType, toManyFromBatch(storage))
+	res.toMany[resourceType] = storage
+}
+
+// SetToOne registers a `PATCH /resource/:id/relationships/<resourceType>` route
+// which replaces the linkage of a One-To-One relationship. The request data is
+// either a single resource identifier, or null to clear the relationship.
+func (res *Resource) SetToOne(resourceType string, storage store.SetToOne) {
+	resourceType = toOneName(resourceType)
+
+	res.handle(
+		patch,
+		relationshipMatcher(resourceType),
+		func(w http.ResponseWriter, r *http.Request) {
+			res.setToOneHandler(w, r, resourceType, storage)
+		},
+	)
+	res.addRoute(Route{
+		Method:       patch,
+		Pattern:      relationshipMatcher(resourceType),
+		Relationship: resourceType,
+		Kind:         RouteRelationship,
+	})
+
+	res.Relationships[resourceType] = ToOne
+}
+
+// AddToMany registers a `POST /resource/:id/relationships/<resourceType>s` route
+// which adds the requested members to a One-To-Many relationship
+func (res *Resource) AddToMany(resourceType string, storage store.AddToMany) {
+	res.toManyUpdate(post, resourceType, storage)
+}
+
+// RemoveFromMany registers a `DELETE /resource/:id/relationships/<resourceType>s`
+// route which removes the requested members from a One-To-Many relationship
+func (res *Resource) RemoveFromMany(resourceType string, storage store.RemoveFromMany) {
+	res.toManyUpdate(delete, resourceType, storage)
+}
+
+// ReplaceToMany registers a `PATCH /resource/:id/relationships/<resourceType>s`
+// route which replaces all members of a One-To-Many relationship
+func (res *Resource) ReplaceToMany(resourceType string, storage store.ReplaceToMany) {
+	res.toManyUpdate(patch, resourceType, storage)
+}
+
+// toManyUpdate registers a route modifying a One-To-Many relationship's linkage
+func (res *Resource) toManyUpdate(
+	method string,
+	resourceType string,
+	storage func(context.Context, string, jsh.ResourceLinkage) (jsh.ResourceLinkage, error),
+) {
+	resourceType = toManyName(resourceType)
+
+	res.handle(
+		method,
+		relationshipMatcher(resourceType),
+		func(w http.ResponseWriter, r *http.Request) {
+			res.toManyUpdateHandler(w, r, resourceType, storage)
 		},
 	)
+	res.addRoute(Route{
+		Method:       method,
+		Pattern:      relationshipMatcher(resourceType),
+		Relationship: resourceType,
+		Kind:         RouteRelationship,
+	})
 
 	res.Relationships[resourceType] = ToMany
 }
 
 // relationshipHandler does the dirty work of setting up both routes for a single
-// relationship
+// relationship, the related resource route and the resource linkage route
 func (res *Resource) relationshipHandler(
 	resourceType string,
-	handler http.HandlerFunc,
+	relatedHandler http.HandlerFunc,
+	linkageHandler http.HandlerFunc,
 ) {
 
 	// handle /.../:id/<resourceType>
 	matcher := fmt.Sprintf("%s/%s", patID, resourceType)
-	res.HandleFunc(
-		pat.Get(matcher),
-		handler,
+	res.handle(
+		get,
+		matcher,
+		relatedHandler,
 	)
-	res.addRoute(get, matcher)
+	res.addRoute(Route{Method: get, Pattern: matcher, Relationship: resourceType, Kind: RouteRelated})
 
 	// handle /.../:id/relationships/<resourceType>
-	relationshipMatcher := fmt.Sprintf("%s/relationships/%s", patID, resourceType)
-	res.HandleFunc(
-		pat.Get(relationshipMatcher),
-		handler,
+	res.handle(
+		get,
+		relationshipMatcher(resourceType),
+		linkageHandler,
 	)
-	res.addRoute(get, relationshipMatcher)
+	res.addRoute(Route{
+		Method:       get,
+		Pattern:      relationshipMatcher(resourceType),
+		Relationship: resourceType,
+		Kind:         RouteRelationship,
+	})
+}
+
+// relationshipMatcher builds the /:id/relationships/<resourceType> route pattern
+func relationshipMatcher(resourceType string) string {
+	return fmt.Sprintf("%s/relationships/%s", patID, resourceType)
+}
+
+// toOneName normalizes the name of a One-To-One relationship to be singular
+func toOneName(resourceType string) string {
+	return strings.TrimSuffix(resourceType, "s")
+}
+
+// toManyName normalizes the name of a One-To-Many relationship to be plural
+func toManyName(resourceType string) string {
+	if !strings.HasSuffix(resourceType, "s") {
+		return fmt.Sprintf("%ss", resourceType)
+	}
+
+	return resourceType
 }
 
 // Action allows you to add custom actions to your resource types, it uses the
@@ -236,64 +492,196 @@ func (res *Resource) relationshipHandler(
 func (res *Resource) Action(actionName string, storage store.Get) {
 	matcher := path.Join(patID, actionName)
 
-	res.HandleFunc(
-		pat.Get(matcher),
+	res.handle(
+		get,
+		matcher,
 		func(w http.ResponseWriter, r *http.Request) {
-			res.actionHandler(w, r, storage)
+			res.actionHandler(w, r, actionName, storage)
 		},
 	)
 
-	res.addRoute(patch, matcher)
+	res.addRoute(Route{Method: get, Pattern: matcher, Kind: RouteAction})
+}
+
+/*
+MethodAction registers a custom action using the given HTTP method via the
+`<method> /(prefix/)resourceTypes/:id/<actionName>` path format. The method must
+be one of GET, POST, PUT, PATCH or DELETE. Storage is passed the request's
+document, or nil if the request has no body, and can respond with any
+jsh.Sendable. Returning nil, or a document with a 204 status, responds with a
+204 No Content:
+
+	resource.MethodAction("POST", "cancel", func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error) {
+		return &jsh.Document{Status: http.StatusAccepted, Meta: map[string]interface{}{"job": "1"}}, nil
+	})
+*/
+func (res *Resource) MethodAction(method string, actionName string, storage store.Action) {
+	method = strings.ToUpper(method)
+	matcher := path.Join(patID, actionName)
+
+	res.handle(
+		method,
+		matcher,
+		func(w http.ResponseWriter, r *http.Request) {
+			res.methodActionHandler(w, r, pat.Param(r, "id"), actionName, storage)
+		},
+	)
+
+	res.addRoute(Route{Method: method, Pattern: matcher, Kind: RouteAction})
+}
+
+/*
+CollectionAction registers a custom action on the resource collection via the
+`<method> /(prefix/)resourceTypes/<actionName>` path format, see MethodAction.
+Storage is called with an empty id. Routes match in the order they are
+registered, so GET collection actions must be registered before Get.
+*/
+func (res *Resource) CollectionAction(method string, actionName string, storage store.Action) {
+	method = strings.ToUpper(method)
+	matcher := path.Join("/", actionName)
+
+	res.handle(
+		method,
+		matcher,
+		func(w http.ResponseWriter, r *http.Request) {
+			res.methodActionHandler(w, r, "", actionName, storage)
+		},
+	)
+
+	res.addRoute(Route{Method: method, Pattern: matcher, Kind: RouteCollectionAction})
 }
 
 // POST /resources
 func (res *Resource) postHandler(w http.ResponseWriter, r *http.Request, storage store.Save) {
-	parsedObject, parseErr := jsh.ParseObject(r)
-	if parseErr != nil && reflect.ValueOf(parseErr).IsNil() == false {
-		SendHandler(w, r, parseErr)
+	parsedObject, parseErr := res.parseObject(r)
+	if parseErr != nil {
+		res.send(w, r, parseErr)
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionCreate, Object: parsedObject}); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	object, err := storage(r.Context(), parsedObject)
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	if err := runHooks(r.Context(), res.hooks.beforeSave, parsedObject); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	SendHandler(w, r, object)
+	object, storageErr := storage(r.Context(), parsedObject)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runHooks(r.Context(), res.hooks.afterSave, object); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.send(w, r, object)
 }
 
 // GET /resources/:id
 func (res *Resource) getHandler(w http.ResponseWriter, r *http.Request, storage store.Get) {
 	id := pat.Param(r, "id")
 
-	object, err := storage(r.Context(), id)
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	if _, err := res.parseFields(r); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionGet, ID: id}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	object, storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	SendHandler(w, r, object)
+	if err := runHooks(r.Context(), res.hooks.afterFetch, object); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.sendIncluded(w, r, object)
 }
 
 // GET /resources
 func (res *Resource) listHandler(w http.ResponseWriter, r *http.Request, storage store.List) {
-	list, err := storage(r.Context())
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	// storage can't sort, so the request must be rejected as per the spec
+	if r.URL.Query().Get(sortParam) != "" {
+		res.send(w, r, jsh.ParameterError("Sorting is not supported", sortParam))
+		return
+	}
+
+	if _, err := res.parseFields(r); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionList}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	// a scope can't be applied by storage without queries, so fail closed
+	scope, scopeErr := res.scope(r)
+	if scopeErr != nil {
+		res.send(w, r, scopeErr)
+		return
+	}
+
+	if len(scope) > 0 {
+		res.send(w, r, jsh.ISE(fmt.Sprintf("Resource '%s' has a scoped policy but no ListWithQuery storage", res.Type)))
+		return
+	}
+
+	list, storageErr := storage(r.Context())
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runHooks(r.Context(), res.hooks.afterFetch, list...); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	SendHandler(w, r, list)
+	res.sendIncluded(w, r, list)
 }
 
 // DELETE /resources/:id
 func (res *Resource) deleteHandler(w http.ResponseWriter, r *http.Request, storage store.Delete) {
+	if err := rejectIfMatch(r); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
 	id := pat.Param(r, "id")
 
-	err := storage(r.Context(), id)
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	if err := res.authorize(r, &PolicyRequest{Action: ActionDelete, ID: id}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runDeleteHooks(r.Context(), res.hooks.beforeDelete, id); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runDeleteHooks(r.Context(), res.hooks.afterDelete, id); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
@@ -302,57 +690,762 @@ func (res *Resource) deleteHandler(w http.ResponseWriter, r *http.Request, stora
 
 // PATCH /resources/:id
 func (res *Resource) patchHandler(w http.ResponseWriter, r *http.Request, storage store.Update) {
-	parsedObject, parseErr := jsh.ParseObject(r)
-	if parseErr != nil && reflect.ValueOf(parseErr).IsNil() == false {
-		SendHandler(w, r, parseErr)
+	if err := rejectIfMatch(r); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	parsedObject, parseErr := res.parseObject(r)
+	if parseErr != nil {
+		res.send(w, r, parseErr)
 		return
 	}
 
 	id := pat.Param(r, "id")
 	if id != parsedObject.ID {
-		SendHandler(w, r, jsh.InputError("Request ID does not match URL's", "id"))
+		res.send(w, r, jsh.InputError("Request ID does not match URL's", "id"))
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionUpdate, ID: id, Object: parsedObject}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runHooks(r.Context(), res.hooks.beforeUpdate, parsedObject); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	object, storageErr := storage(r.Context(), parsedObject)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runHooks(r.Context(), res.hooks.afterUpdate, object); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.send(w, r, object)
+}
+
+// PATCH /resources/:id with If-Match
+func (res *Resource) versionedPatchHandler(w http.ResponseWriter, r *http.Request, storage store.VersionedUpdate) {
+	versions, preconditionErr := res.expectedVersions(r)
+	if preconditionErr != nil {
+		res.send(w, r, preconditionErr)
+		return
+	}
+
+	version, matchErr := res.matchVersion(r, pat.Param(r, "id"), versions)
+	if matchErr != nil {
+		res.send(w, r, matchErr)
+		return
+	}
+
+	parsedObject, parseErr := res.parseObject(r)
+	if parseErr != nil {
+		res.send(w, r, parseErr)
+		return
+	}
+
+	id := pat.Param(r, "id")
+	if id != parsedObject.ID {
+		res.send(w, r, jsh.InputError("Request ID does not match URL's", "id"))
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionUpdate, ID: id, Object: parsedObject}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runHooks(r.Context(), res.hooks.beforeUpdate, parsedObject); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	object, storageErr := storage.UpdateVersion(r.Context(), parsedObject, version)
+	if err := versionError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runHooks(r.Context(), res.hooks.afterUpdate, object); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.send(w, r, object)
+}
+
+// DELETE /resources/:id with If-Match
+func (res *Resource) versionedDeleteHandler(w http.ResponseWriter, r *http.Request, storage store.VersionedDelete) {
+	versions, preconditionErr := res.expectedVersions(r)
+	if preconditionErr != nil {
+		res.send(w, r, preconditionErr)
+		return
+	}
+
+	id := pat.Param(r, "id")
+
+	version, matchErr := res.matchVersion(r, id, versions)
+	if matchErr != nil {
+		res.send(w, r, matchErr)
+		return
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionDelete, ID: id}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runDeleteHooks(r.Context(), res.hooks.beforeDelete, id); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	storageErr := storage.DeleteVersion(r.Context(), id, version)
+	if err := versionError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := runDeleteHooks(r.Context(), res.hooks.afterDelete, id); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /resources/:id/<resourceType>
+func (res *Resource) toOneHandler(w http.ResponseWriter, r *http.Request, relationship string, storage store.Get) {
+	id := pat.Param(r, "id")
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionGetRelationship, ID: id, Relationship: relationship}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	object, storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	// an empty relationship is sent as null data
+	if object == nil {
+		document := apiFromRequest(r).config().New()
+		document.Status = http.StatusOK
+		res.send(w, r, document)
+		return
+	}
+
+	if err := apiFromRequest(r).authorizeRelated(r.Context(), object); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := apiFromRequest(r).afterFetch(r.Context(), object); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.send(w, r, object)
+}
+
+// GET /resources/:id/<resourceType>s
+func (res *Resource) toManyHandler(w http.ResponseWriter, r *http.Request, relationship string, storage store.ToMany) {
+	id := pat.Param(r, "id")
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionGetRelationship, ID: id, Relationship: relationship}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	list, storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	list, err := apiFromRequest(r).visibleRelated(r.Context(), list)
+	if err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	if err := apiFromRequest(r).afterFetch(r.Context(), list...); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.send(w, r, list)
+}
+
+// GET /resources/:id/relationships/<resourceType>
+func (res *Resource) toOneLinkageHandler(w http.ResponseWriter, r *http.Request, relationship string, storage store.Get) {
+	id := pat.Param(r, "id")
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionGetRelationship, ID: id, Relationship: relationship}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	object, storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	related := jsh.List{}
+	if object != nil {
+		related = append(related, object)
+	}
+
+	related, err := apiFromRequest(r).visibleRelated(r.Context(), related)
+	if err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	linkage := related.Linkage()
+
+	res.send(w, r, &jsh.Relationship{
+		Links: res.relationshipLinks(apiFromRequest(r), id, relationship),
+		Data:  linkage,
+		ToOne: true,
+	})
+}
+
+// GET /resources/:id/relationships/<resourceType>s
+func (res *Resource) toManyLinkageHandler(w http.ResponseWriter, r *http.Request, relationship string, storage store.ToMany) {
+	id := pat.Param(r, "id")
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionGetRelationship, ID: id, Relationship: relationship}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	list, storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	object, err := storage(r.Context(), parsedObject)
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	list, err := apiFromRequest(r).visibleRelated(r.Context(), list)
+	if err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	SendHandler(w, r, object)
+	res.send(w, r, &jsh.Relationship{
+		Links: res.relationshipLinks(apiFromRequest(r), id, relationship),
+		Data:  list.Linkage(),
+	})
 }
 
-// GET /resources/:id/(relationships/)<resourceType>s
-func (res *Resource) toManyHandler(w http.ResponseWriter, r *http.Request, storage store.ToMany) {
+// PATCH /resources/:id/relationships/<resourceType>
+func (res *Resource) setToOneHandler(w http.ResponseWriter, r *http.Request, relationship string, storage store.SetToOne) {
+	document, parseErr := apiFromRequest(r).config().ParseDoc(r, jsh.LinkageMode)
+	if parseErr != nil {
+		res.send(w, r, parseErr)
+		return
+	}
+
+	// arrays are rejected even with a single member, as per the JSON API spec
+	if !document.Linkage.ToOne {
+		err := jsh.RelationshipError("A to-one relationship must be set to a single resource identifier or null", relationship)
+		err.Source.Pointer = "/data"
+		res.send(w, r, err)
+		return
+	}
+
+	if err := res.checkLinkage(relationship, document.Linkage.Data); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	var related *jsh.ResourceIdentifier
+	if len(document.Linkage.Data) == 1 {
+		related = document.Linkage.Data[0]
+	}
+
+	id := pat.Param(r, "id")
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionUpdateRelationship, ID: id, Relationship: relationship}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	updated, storageErr := storage(r.Context(), id, related)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	linkage := jsh.ResourceLinkage{}
+	if updated != nil {
+		linkage = append(linkage, updated)
+	}
+
+	res.send(w, r, &jsh.Relationship{
+		Links: res.relationshipLinks(apiFromRequest(r), id, relationship),
+		Data:  linkage,
+		ToOne: true,
+	})
+}
+
+// POST, PATCH, DELETE /resources/:id/relationships/<resourceType>s
+func (res *Resource) toManyUpdateHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	relationship string,
+	storage func(context.Context, string, jsh.ResourceLinkage) (jsh.ResourceLinkage, error),
+) {
+	document, parseErr := apiFromRequest(r).config().ParseDoc(r, jsh.LinkageMode)
+	if parseErr != nil {
+		res.send(w, r, parseErr)
+		return
+	}
+
+	// a single identifier is rejected, as per the JSON API spec
+	if document.Linkage.ToOne {
+		err := jsh.RelationshipError("A to-many relationship must be given an array of resource identifiers", relationship)
+		err.Source.Pointer = "/data"
+		res.send(w, r, err)
+		return
+	}
+
+	linkage := document.Linkage.Data
+	if err := res.checkLinkage(relationship, linkage); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
 	id := pat.Param(r, "id")
 
-	list, err := storage(r.Context(), id)
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	if err := res.authorize(r, &PolicyRequest{Action: ActionUpdateRelationship, ID: id, Relationship: relationship}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	updated, storageErr := storage(r.Context(), id, linkage)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	SendHandler(w, r, list)
+	res.send(w, r, &jsh.Relationship{
+		Links: res.relationshipLinks(apiFromRequest(r), id, relationship),
+		Data:  updated,
+	})
 }
 
 // All HTTP Methods for /resources/:id/<mutate>
-func (res *Resource) actionHandler(w http.ResponseWriter, r *http.Request, storage store.Get) {
+func (res *Resource) actionHandler(w http.ResponseWriter, r *http.Request, name string, storage store.Get) {
 	id := pat.Param(r, "id")
 
-	response, err := storage(r.Context(), id)
-	if err != nil && reflect.ValueOf(err).IsNil() == false {
-		SendHandler(w, r, err)
+	if err := res.authorize(r, &PolicyRequest{Action: ActionCustom, ID: id, Name: name}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	response, storageErr := storage(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	res.send(w, r, response)
+}
+
+// <method> /resources(/:id)/<action>
+func (res *Resource) methodActionHandler(
+	w http.ResponseWriter,
+	r *http.Request,
+	id string,
+	name string,
+	storage store.Action,
+) {
+	var request *jsh.Document
+	if r.ContentLength != 0 {
+		document, parseErr := apiFromRequest(r).config().ParseDoc(r, jsh.ObjectMode)
+		if parseErr != nil {
+			res.send(w, r, parseErr)
+			return
+		}
+
+		request = document
+	}
+
+	if err := res.authorize(r, &PolicyRequest{Action: ActionCustom, ID: id, Name: name}); err != nil {
+		res.send(w, r, err)
+		return
+	}
+
+	response, storageErr := storage(r.Context(), id, request)
+	if err := jsh.MapError(storageErr); err != nil {
+		res.send(w, r, err)
 		return
 	}
 
-	SendHandler(w, r, response)
+	if isEmptyResponse(response) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	res.send(w, r, response)
+}
+
+// isEmptyResponse reports whether an action responded with nothing to send,
+// including typed nils wrapped in the Sendable interface
+func isEmptyResponse(response jsh.Sendable) bool {
+	switch sendable := response.(type) {
+	case nil:
+		return true
+	case *jsh.Document:
+		return sendable == nil || sendable.Status == http.StatusNoContent
+	case *jsh.Object:
+		return sendable == nil
+	case jsh.List:
+		return sendable == nil
+	case *jsh.Error:
+		return sendable == nil
+	case jsh.ErrorList:
+		return sendable == nil
+	default:
+		return false
+	}
+}
+
+// send responds using the Sender of the API serving the request, after adding
+// links to the objects being sent and hiding their attributes, see prepare
+func (res *Resource) send(w http.ResponseWriter, r *http.Request, payload jsh.Sendable) {
+	api := apiFromRequest(r)
+
+	prepared, err := res.prepare(r.Context(), api, payload)
+	if err != nil {
+		prepared = err
+	}
+
+	api.sender()(w, r, prepared)
+}
+
+/*
+prepare adds links to the objects of a payload, and returns the payload with
+copies of the objects that have attributes hidden from the caller.
+*/
+func (res *Resource) prepare(ctx context.Context, api *API, payload jsh.Sendable) (jsh.Sendable, jsh.ErrorType) {
+	switch sendable := payload.(type) {
+	case *jsh.Object:
+		object, err := res.prepareObject(ctx, api, sendable)
+		if err != nil {
+			return nil, err
+		}
+		return object, nil
+	case jsh.List:
+		return res.prepareList(ctx, api, sendable)
+	case *jsh.Document:
+		data, err := res.prepareList(ctx, api, sendable.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		included, err := res.prepareList(ctx, api, sendable.Included)
+		if err != nil {
+			return nil, err
+		}
+
+		document := *sendable
+		document.Data = data
+		document.Included = included
+		return &document, nil
+	}
+
+	return payload, nil
+}
+
+// prepareList prepares each object of a list, see prepare
+func (res *Resource) prepareList(ctx context.Context, api *API, list jsh.List) (jsh.List, jsh.ErrorType) {
+	if list == nil {
+		return nil, nil
+	}
+
+	prepared := make(jsh.List, len(list))
+	for i, object := range list {
+		var err jsh.ErrorType
+		prepared[i], err = res.prepareObject(ctx, api, object)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return prepared, nil
+}
+
+// prepareObject prepares a single object, see prepare
+func (res *Resource) prepareObject(ctx context.Context, api *API, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if object == nil {
+		return nil, nil
+	}
+
+	api.addLinks(object)
+
+	resource := res.resourceOf(api, object.Type)
+	if resource == nil {
+		return object, nil
+	}
+
+	return resource.hideAttributes(ctx, object)
+}
+
+// resourceOf returns the resource serving a type, which is only res itself when
+// the request isn't served by an API. Returns nil if no resource serves it.
+func (res *Resource) resourceOf(api *API, resourceType string) *Resource {
+	if api == nil {
+		if resourceType == res.Type {
+			return res
+		}
+		return nil
+	}
+
+	return api.Resources[resourceType]
+}
+
+// parseObject parses the request using the Config of the API serving the request,
+// rejecting attributes that the caller may not write or that violate the schema
+func (res *Resource) parseObject(r *http.Request) (*jsh.Object, jsh.ErrorType) {
+	object, parseErr := apiFromRequest(r).config().ParseObject(r)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	writeErr := res.checkWritable(r.Context(), object)
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	schemaErr := res.validateSchema(r, object)
+	if schemaErr != nil {
+		return nil, schemaErr
+	}
+
+	return object, nil
+}
+
+/*
+expectedVersions extracts the versions listed by the request's If-Match header,
+see matchVersion. Weak ETags can never satisfy If-Match and are skipped, a "*"
+accepts any version. Requests without If-Match expect the "" version.
+*/
+func (res *Resource) expectedVersions(r *http.Request) ([]string, *jsh.Error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		if res.RequireIfMatch {
+			return nil, jsh.PreconditionRequired("This request requires an If-Match header containing the resource's ETag")
+		}
+
+		return []string{""}, nil
+	}
+
+	versions := []string{}
+	for _, etag := range strings.Split(ifMatch, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" {
+			return []string{"*"}, nil
+		}
+
+		version := jsh.ETagVersion(etag)
+		if version != "" {
+			versions = append(versions, version)
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil, jsh.PreconditionFailed("If-Match must contain a strong ETag")
+	}
+
+	return versions, nil
+}
+
+/*
+matchVersion picks the version storage is asked to match, so that storage is
+called once per request. A list of versions is resolved against the current
+version of the resource, fetched via the storage registered with Get, a 412 is
+sent if none of them matches.
+*/
+func (res *Resource) matchVersion(r *http.Request, id string, versions []string) (string, jsh.ErrorType) {
+	if len(versions) == 1 {
+		return versions[0], nil
+	}
+
+	if res.get == nil {
+		return "", jsh.PreconditionFailed("If-Match must contain a single ETag for this resource")
+	}
+
+	object, storageErr := res.get(r.Context(), id)
+	if err := jsh.MapError(storageErr); err != nil {
+		return "", err
+	}
+
+	current := ""
+	if object != nil {
+		current = object.Version()
+	}
+
+	for _, version := range versions {
+		if current != "" && version == current {
+			return version, nil
+		}
+	}
+
+	return "", jsh.PreconditionFailed("The resource has been modified since it was last fetched")
+}
+
+/*
+rejectIfMatch fails requests with an If-Match precondition other than "*" for
+storage that doesn't support versions, as the precondition cannot be evaluated
+*/
+func rejectIfMatch(r *http.Request) *jsh.Error {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+
+	return jsh.PreconditionFailed("This resource does not support If-Match preconditions")
+}
+
+// versionError maps storage errors, responding to a version mismatch with a 412
+func versionError(storageErr error) jsh.ErrorType {
+	if errors.Is(storageErr, store.ErrVersionMismatch) {
+		err := jsh.PreconditionFailed("The resource has been modified since it was last fetched")
+		err.Err = storageErr
+		return err
+	}
+
+	return jsh.MapError(storageErr)
+}
+
+/*
+handle registers a handler for a method and route pattern. The first handler
+registered for a pattern also registers an OPTIONS handler for it, so that the
+methods supported by each route can be discovered. GET handlers also serve HEAD.
+*/
+func (res *Resource) handle(method string, matcher string, handler http.HandlerFunc) {
+	var pattern *pat.Pattern
+	switch method {
+	case get:
+		pattern = pat.Get(matcher)
+	case post:
+		pattern = pat.Post(matcher)
+	case patch:
+		pattern = pat.Patch(matcher)
+	case put:
+		pattern = pat.Put(matcher)
+	case delete:
+		pattern = pat.Delete(matcher)
+	default:
+		panic(fmt.Sprintf("jshapi: unsupported route method %s", method))
+	}
+
+	res.HandleFunc(pattern, handler)
+
+	if res.methods == nil {
+		res.methods = map[string][]string{}
+	}
+
+	_, registered := res.methods[matcher]
+	res.methods[matcher] = append(res.methods[matcher], method)
+
+	if !registered {
+		res.HandleFunc(pat.Options(matcher), func(w http.ResponseWriter, r *http.Request) {
+			res.optionsHandler(w, r, matcher)
+		})
+	}
+}
+
+// OPTIONS /resources(/:id...)
+func (res *Resource) optionsHandler(w http.ResponseWriter, r *http.Request, matcher string) {
+	w.Header().Set("Allow", res.allow(matcher))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+unmatchedMiddleware dispatches requests that don't match any registered route to
+unmatchedHandler. The handler is swapped rather than called directly so that the
+rest of the middleware stack, such as logging, still runs.
+*/
+func (res *Resource) unmatchedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.Handler(r.Context()) == nil {
+			ctx := middleware.SetHandler(r.Context(), http.HandlerFunc(res.unmatchedHandler))
+			r = r.WithContext(ctx)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+/*
+unmatchedHandler responds with a 405 Method Not Allowed if the path matches a
+registered route, otherwise with a 404 Not Found.
+*/
+func (res *Resource) unmatchedHandler(w http.ResponseWriter, r *http.Request) {
+	matchers := make([]string, 0, len(res.methods))
+	for matcher := range res.methods {
+		matchers = append(matchers, matcher)
+	}
+	sort.Strings(matchers)
+
+	for _, matcher := range matchers {
+		if pat.New(matcher).Match(r) != nil {
+			w.Header().Set("Allow", res.allow(matcher))
+			res.send(w, r, jsh.MethodNotAllowed(r.Method))
+			return
+		}
+	}
+
+	res.send(w, r, jsh.RouteNotFound(r.URL.Path))
+}
+
+// allow builds the Allow header value for a route pattern, GET implies HEAD
+func (res *Resource) allow(matcher string) string {
+	registered := map[string]bool{options: true}
+	for _, method := range res.methods[matcher] {
+		registered[method] = true
+		if method == get {
+			registered[head] = true
+		}
+	}
+
+	allowed := []string{}
+	for _, method := range []string{get, head, post, put, patch, delete, options} {
+		if registered[method] {
+			allowed = append(allowed, method)
+		}
+	}
+
+	return strings.Join(allowed, ", ")
+}
+
+// relationshipLinks builds the self and related links of a relationship
+func (res *Resource) relationshipLinks(api *API, id string, relationship string) *jsh.Links {
+	return &jsh.Links{
+		Self:    jsh.NewLink(api.resourcePath(res.Type, id, "relationships", relationship)),
+		Related: jsh.NewLink(api.resourcePath(res.Type, id, relationship)),
+	}
 }
 
-// addRoute adds the new method and route to a route Tree for debugging and
-// informational purposes.
-func (res *Resource) addRoute(method string, route string) {
-	res.Routes = append(res.Routes, fmt.Sprintf("%s - /%s%s", method, res.Type, route))
+// addRoute adds the new route to a route Tree for debugging and informational
+// purposes, as well as to the routes listed by API.Routes()
+func (res *Resource) addRoute(route Route) {
+	route.Resource = res.Type
+	res.routes = append(res.routes, route)
+	res.Routes = append(res.Routes, fmt.Sprintf("%s - /%s%s", route.Method, res.Type, route.Pattern))
 }
 
 // RouteTree prints a recursive route tree based on what the resource, and
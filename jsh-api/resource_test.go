@@ -2,14 +2,19 @@ package jshapi
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/derekdowling/go-json-spec-handler"
 	"github.com/derekdowling/go-json-spec-handler/client"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -98,6 +103,51 @@ func TestResource(t *testing.T) {
 			So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
 			So(err, ShouldBeNil)
 		})
+
+		Convey("HEAD", func() {
+
+			Convey("should send GET headers without a body", func() {
+				resp, err := http.Head(baseURL + "/" + testResourceType + "/1")
+				So(err, ShouldBeNil)
+				defer resp.Body.Close()
+
+				body, err := ioutil.ReadAll(resp.Body)
+				So(err, ShouldBeNil)
+
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(resp.Header.Get("Content-Type"), ShouldEqual, jsh.ContentType)
+				So(resp.ContentLength, ShouldBeGreaterThan, 0)
+				So(body, ShouldBeEmpty)
+			})
+		})
+
+		Convey("OPTIONS", func() {
+
+			options := func(path string) *http.Response {
+				request, err := http.NewRequest("OPTIONS", baseURL+path, nil)
+				So(err, ShouldBeNil)
+
+				resp, err := http.DefaultClient.Do(request)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+
+				return resp
+			}
+
+			Convey("should list the methods of the collection", func() {
+				resp := options("/" + testResourceType)
+
+				So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
+				So(resp.Header.Get("Allow"), ShouldEqual, "GET, HEAD, POST, OPTIONS")
+			})
+
+			Convey("should list the methods of an object", func() {
+				resp := options("/" + testResourceType + "/1")
+
+				So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
+				So(resp.Header.Get("Allow"), ShouldEqual, "GET, HEAD, PATCH, DELETE, OPTIONS")
+			})
+		})
 	})
 }
 
@@ -106,7 +156,7 @@ func TestActionHandler(t *testing.T) {
 	resource := NewMockResource(testResourceType, 2, testObjAttrs)
 
 	// Add our custom action
-	handler := func(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	handler := func(ctx context.Context, id string) (*jsh.Object, error) {
 		object := sampleObject(id, testResourceType, testObjAttrs)
 		return object, nil
 	}
@@ -122,7 +172,7 @@ func TestActionHandler(t *testing.T) {
 
 		Convey("Resource State", func() {
 			So(len(resource.Routes), ShouldEqual, 6)
-			So(resource.Routes[len(resource.Routes)-1], ShouldEqual, "PATCH - /bars/:id/testAction")
+			So(resource.Routes[len(resource.Routes)-1], ShouldEqual, "GET - /bars/:id/testAction")
 		})
 
 		Convey("->Custom()", func() {
@@ -135,11 +185,87 @@ func TestActionHandler(t *testing.T) {
 	})
 }
 
+func TestMethodActionHandler(t *testing.T) {
+
+	var received *jsh.Document
+
+	resource := NewMockResource(testResourceType, 2, testObjAttrs)
+	resource.MethodAction("post", "cancel", func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error) {
+		received = request
+		if id == "2" {
+			return nil, nil
+		}
+
+		return &jsh.Document{Status: http.StatusAccepted, Meta: map[string]interface{}{"id": id}}, nil
+	})
+	resource.CollectionAction("POST", "import", func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error) {
+		return jsh.List{sampleObject("1", testResourceType, testObjAttrs)}, nil
+	})
+	resource.MethodAction("PUT", "archive", func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error) {
+		var archived *jsh.Object
+		return archived, nil
+	})
+
+	api := New("")
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	Convey("Method Action Handler Tests", t, func() {
+
+		Reset(func() {
+			received = nil
+		})
+
+		Convey("Resource State", func() {
+			So(resource.Routes[len(resource.Routes)-3], ShouldEqual, "POST - /bars/:id/cancel")
+			So(resource.Routes[len(resource.Routes)-2], ShouldEqual, "POST - /bars/import")
+			So(resource.Routes[len(resource.Routes)-1], ShouldEqual, "PUT - /bars/:id/archive")
+		})
+
+		Convey("should pass the request document and send the response", func() {
+			request := &jsh.Document{Meta: map[string]interface{}{"reason": "duplicate"}}
+
+			doc, resp, err := jsc.MethodAction("POST", server.URL, testResourceType, "1", "cancel", request)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusAccepted)
+			So(doc.Meta, ShouldResemble, map[string]interface{}{"id": "1"})
+			So(received.Meta, ShouldResemble, map[string]interface{}{"reason": "duplicate"})
+		})
+
+		Convey("should respond with a 204 without a response", func() {
+			_, resp, err := jsc.MethodAction("POST", server.URL, testResourceType, "2", "cancel", nil)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
+			So(received, ShouldBeNil)
+		})
+
+		Convey("should serve collection actions", func() {
+			doc, resp, err := jsc.MethodAction("POST", server.URL, testResourceType, "", "import", nil)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.First().ID, ShouldEqual, "1")
+		})
+
+		Convey("should serve PUT actions and respond with a 204 for typed nils", func() {
+			_, resp, err := jsc.MethodAction("PUT", server.URL, testResourceType, "1", "archive", nil)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
+
+			request, _ := http.NewRequest("OPTIONS", server.URL+"/bars/1/archive", nil)
+			resp, err = http.DefaultClient.Do(request)
+			So(err, ShouldBeNil)
+			So(resp.Header.Get("Allow"), ShouldEqual, "PUT, OPTIONS")
+		})
+	})
+}
+
 func TestToOne(t *testing.T) {
 
 	resource := NewMockResource(testResourceType, 2, testObjAttrs)
 
-	relationshipHandler := func(ctx context.Context, resourceID string) (*jsh.Object, jsh.ErrorType) {
+	relationshipHandler := func(ctx context.Context, resourceID string) (*jsh.Object, error) {
 		return sampleObject("1", "baz", map[string]string{"baz": "ball"}), nil
 	}
 
@@ -183,6 +309,20 @@ func TestToOne(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(doc.Data[0].ID, ShouldEqual, "1")
 			})
+
+			Convey("/foo/bars/:id/relationships/baz should return resource linkage", func() {
+				request, err := jsc.ActionRequest(baseURL, testResourceType, "1", "relationships/"+subResourceType)
+				So(err, ShouldBeNil)
+
+				doc, resp, err := jsc.Do(request, jsh.LinkageMode)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+				So(doc.Linkage.ToOne, ShouldBeTrue)
+				So(doc.Linkage.Data[0].Type, ShouldEqual, "baz")
+				So(doc.Links.Self.HREF, ShouldEqual, "/bars/1/relationships/baz")
+				So(doc.Links.Related.HREF, ShouldEqual, "/bars/1/baz")
+			})
 		})
 	})
 }
@@ -191,7 +331,7 @@ func TestToMany(t *testing.T) {
 
 	resource := NewMockResource(testResourceType, 2, testObjAttrs)
 
-	relationshipHandler := func(ctx context.Context, resourceID string) (jsh.List, jsh.ErrorType) {
+	relationshipHandler := func(ctx context.Context, resourceID string) (jsh.List, error) {
 		return jsh.List{
 			sampleObject("1", "baz", map[string]string{"baz": "ball"}),
 			sampleObject("2", "baz", map[string]string{"baz": "ball2"}),
@@ -240,6 +380,302 @@ func TestToMany(t *testing.T) {
 				So(len(doc.Data), ShouldEqual, 2)
 				So(doc.Data[0].ID, ShouldEqual, "1")
 			})
+
+			Convey("/foo/bars/:id/relationships/bazs should return resource linkage", func() {
+				request, err := jsc.ActionRequest(baseURL, testResourceType, "1", "relationships/"+subResourceType+"s")
+				So(err, ShouldBeNil)
+
+				doc, resp, err := jsc.Do(request, jsh.LinkageMode)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+				So(doc.Linkage.ToOne, ShouldBeFalse)
+				So(len(doc.Linkage.Data), ShouldEqual, 2)
+				So(doc.Links.Related.HREF, ShouldEqual, "/bars/1/bazs")
+			})
+		})
+	})
+}
+
+func TestStorageErrors(t *testing.T) {
+
+	resource := NewResource(testResourceType)
+	resource.Get(func(ctx context.Context, id string) (*jsh.Object, error) {
+		if id == "1" {
+			return nil, errors.New("connection refused")
+		}
+
+		var err *jsh.Error
+		return sampleObject(id, testResourceType, testObjAttrs), err
+	})
+
+	api := New("")
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	baseURL := server.URL
+
+	Convey("Storage Error Tests", t, func() {
+
+		Convey("should map plain Go errors to an ISE", func() {
+			doc, resp, err := jsc.Fetch(baseURL, testResourceType, "1")
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusInternalServerError)
+			So(doc.HasErrors(), ShouldBeTrue)
+		})
+
+		Convey("should ignore typed nil errors", func() {
+			doc, resp, err := jsc.Fetch(baseURL, testResourceType, "2")
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.First().ID, ShouldEqual, "2")
+		})
+	})
+}
+
+// versionedStorage is a single object store that tracks a version number
+type versionedStorage struct {
+	MockStorage
+	version int
+	// updates counts the calls to UpdateVersion
+	updates int
+}
+
+func (v *versionedStorage) Get(ctx context.Context, id string) (*jsh.Object, error) {
+	object := v.SampleObject(id)
+	object.Meta = map[string]interface{}{jsh.MetaVersion: strconv.Itoa(v.version)}
+
+	return object, nil
+}
+
+func (v *versionedStorage) UpdateVersion(ctx context.Context, object *jsh.Object, version string) (*jsh.Object, error) {
+	v.updates++
+	if version != "" && version != "*" && version != strconv.Itoa(v.version) {
+		return nil, store.ErrVersionMismatch
+	}
+
+	v.version++
+	return v.Get(ctx, object.ID)
+}
+
+func (v *versionedStorage) DeleteVersion(ctx context.Context, id string, version string) error {
+	if version != "" && version != "*" && version != strconv.Itoa(v.version) {
+		return fmt.Errorf("delete %s: %w", id, store.ErrVersionMismatch)
+	}
+
+	return nil
+}
+
+func TestVersionedResource(t *testing.T) {
+
+	storage := &versionedStorage{
+		MockStorage: MockStorage{ResourceType: testResourceType, ResourceAttributes: testObjAttrs},
+		version:     1,
+	}
+
+	resource := NewCRUDResource(testResourceType, storage)
+
+	api := New("")
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	baseURL := server.URL
+
+	Convey("Versioned Resource Tests", t, func() {
+
+		Convey("should send the object version as an ETag", func() {
+			doc, resp, err := jsc.Fetch(baseURL, testResourceType, "1")
+
+			So(err, ShouldBeNil)
+			So(resp.Header.Get("ETag"), ShouldStartWith, `"1;`)
+			So(doc.First().ETag, ShouldEqual, resp.Header.Get("ETag"))
+		})
+
+		Convey("should PATCH with If-Match from the fetched document", func() {
+			storage.version = 1
+			doc, _, err := jsc.Fetch(baseURL, testResourceType, "1")
+			So(err, ShouldBeNil)
+
+			patchDoc, resp, err := jsc.Patch(baseURL, doc.First())
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(jsh.ETagVersion(patchDoc.First().ETag), ShouldEqual, "2")
+
+			Convey("and reject stale versions with a 412", func() {
+				staleDoc, resp, err := jsc.Patch(baseURL, doc.First())
+
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusPreconditionFailed)
+				So(staleDoc.Errors[0].Code, ShouldEqual, jsh.CodePreconditionFailed)
+			})
+		})
+
+		Convey("should reject DELETE with a stale If-Match", func() {
+			storage.version = 3
+
+			request, err := jsc.DeleteRequest(baseURL, testResourceType, "1")
+			So(err, ShouldBeNil)
+			request.Header.Set("If-Match", `"2"`)
+
+			_, resp, err := jsc.Do(request, jsh.ObjectMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusPreconditionFailed)
+		})
+
+		Convey("should accept an If-Match list containing the current version", func() {
+			storage.version = 3
+
+			request, err := jsc.DeleteRequest(baseURL, testResourceType, "1")
+			So(err, ShouldBeNil)
+			request.Header.Set("If-Match", `"2", W/"3", "3"`)
+
+			_, resp, err := jsc.Do(request, jsh.ObjectMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNoContent)
+		})
+
+		Convey("should call storage once for an If-Match list", func() {
+			storage.version = 3
+			storage.updates = 0
+
+			patchList := func(ifMatch string) *http.Response {
+				request, err := jsc.PatchRequest(baseURL, sampleObject("1", testResourceType, testObjAttrs))
+				So(err, ShouldBeNil)
+				request.Header.Set("If-Match", ifMatch)
+
+				_, resp, err := jsc.Do(request, jsh.ObjectMode)
+				So(err, ShouldBeNil)
+				return resp
+			}
+
+			So(patchList(`"1", "2"`).StatusCode, ShouldEqual, http.StatusPreconditionFailed)
+			So(storage.updates, ShouldEqual, 0)
+
+			So(patchList(`"1", "3;a1b2c3d4e5f6"`).StatusCode, ShouldEqual, http.StatusOK)
+			So(storage.updates, ShouldEqual, 1)
+		})
+
+		Convey("should require If-Match if configured", func() {
+			resource.RequireIfMatch = true
+			defer func() { resource.RequireIfMatch = false }()
+
+			object := sampleObject("1", testResourceType, testObjAttrs)
+			doc, resp, err := jsc.Patch(baseURL, object)
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusPreconditionRequired)
+			So(doc.Errors[0].Code, ShouldEqual, jsh.CodePreconditionRequired)
+		})
+	})
+}
+
+func TestRelationshipUpdates(t *testing.T) {
+
+	resource := NewMockResource(testResourceType, 2, testObjAttrs)
+
+	author := &jsh.ResourceIdentifier{Type: "baz", ID: "1"}
+	resource.SetToOne("baz", func(ctx context.Context, id string, related *jsh.ResourceIdentifier) (*jsh.ResourceIdentifier, error) {
+		author = related
+		return author, nil
+	})
+
+	tags := jsh.ResourceLinkage{}
+	resource.AddToMany("tag", func(ctx context.Context, id string, related jsh.ResourceLinkage) (jsh.ResourceLinkage, error) {
+		tags = append(tags, related...)
+		return tags, nil
+	})
+	resource.ReplaceToMany("tag", func(ctx context.Context, id string, related jsh.ResourceLinkage) (jsh.ResourceLinkage, error) {
+		tags = related
+		return tags, nil
+	})
+	resource.RemoveFromMany("tag", func(ctx context.Context, id string, related jsh.ResourceLinkage) (jsh.ResourceLinkage, error) {
+		tags = jsh.ResourceLinkage{}
+		return tags, nil
+	})
+
+	resource.SetSchema((&Schema{}).ToOne("baz", "baz").ToMany("tags", "tag"))
+
+	api := New("")
+	api.Add(resource)
+
+	server := httptest.NewServer(api)
+	baseURL := server.URL + "/" + testResourceType + "/1/relationships/"
+
+	send := func(method string, relationship string, body string, mode jsh.DocumentMode) (*jsh.Document, *http.Response) {
+		request, err := jsc.NewRequest(method, baseURL+relationship, strings.NewReader(body))
+		So(err, ShouldBeNil)
+
+		doc, resp, err := jsc.Do(request, mode)
+		So(err, ShouldBeNil)
+
+		return doc, resp
+	}
+
+	Convey("Relationship Update Tests", t, func() {
+
+		Convey("Resource State", func() {
+			So(len(resource.Routes), ShouldEqual, 9)
+			So(len(resource.Relationships), ShouldEqual, 2)
+		})
+
+		Convey("->SetToOne()", func() {
+
+			Convey("should replace the linkage", func() {
+				doc, resp := send("PATCH", "baz", `{"data": {"type": "baz", "id": "2"}}`, jsh.ObjectMode)
+
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(doc.First().ID, ShouldEqual, "2")
+				So(author.ID, ShouldEqual, "2")
+			})
+
+			Convey("should clear the linkage", func() {
+				doc, resp := send("PATCH", "baz", `{"data": null}`, jsh.ObjectMode)
+
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(doc.HasData(), ShouldBeFalse)
+				So(author, ShouldBeNil)
+			})
+
+			Convey("should reject arrays", func() {
+				doc, resp := send("PATCH", "baz", `{"data": [{"type": "baz", "id": "2"}]}`, jsh.ObjectMode)
+
+				So(resp.StatusCode, ShouldEqual, 422)
+				So(doc.Errors[0].Source.Pointer, ShouldEqual, "/data")
+			})
+
+			Convey("should reject linkage to resources of the wrong type", func() {
+				doc, resp := send("PATCH", "baz", `{"data": {"type": "tag", "id": "2"}}`, jsh.ObjectMode)
+
+				So(resp.StatusCode, ShouldEqual, 422)
+				So(doc.Errors[0].Source.Pointer, ShouldEqual, "/data")
+			})
+		})
+
+		Convey("->AddToMany(), ->ReplaceToMany(), ->RemoveFromMany()", func() {
+			doc, resp := send("POST", "tags", `{"data": [{"type": "tag", "id": "1"}]}`, jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Data), ShouldEqual, 1)
+
+			doc, resp = send("PATCH", "tags", `{"data": [{"type": "tag", "id": "2"}, {"type": "tag", "id": "3"}]}`, jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(len(doc.Data), ShouldEqual, 2)
+			So(doc.Data[0].ID, ShouldEqual, "2")
+
+			doc, resp = send("DELETE", "tags", `{"data": [{"type": "tag", "id": "2"}]}`, jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.Data, ShouldBeEmpty)
+		})
+
+		Convey("should reject invalid to-many linkage", func() {
+			doc, resp := send("POST", "tags", `{"data": {"type": "tag", "id": "1"}}`, jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, 422)
+			So(doc.Errors[0].Source.Pointer, ShouldEqual, "/data")
+
+			_, resp = send("PATCH", "tags", `{"data": [{"type": "baz", "id": "1"}]}`, jsh.ListMode)
+			So(resp.StatusCode, ShouldEqual, 422)
+			So(tags, ShouldBeEmpty)
 		})
 	})
 }
@@ -0,0 +1,103 @@
+package jshapi
+
+import (
+	"net/http"
+
+	"goji.io/pat"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+// RouteKind describes what a route's handler does
+type RouteKind string
+
+// Kinds of routes registered by a Resource
+const (
+	RouteList         RouteKind = "list"
+	RouteCreate       RouteKind = "create"
+	RouteGet          RouteKind = "get"
+	RouteUpdate       RouteKind = "update"
+	RouteDelete       RouteKind = "delete"
+	RouteRelated      RouteKind = "related"
+	RouteRelationship RouteKind = "relationship"
+	RouteAction       RouteKind = "action"
+	// RouteCollectionAction routes act on a resource collection rather than an
+	// individual resource
+	RouteCollectionAction RouteKind = "collection_action"
+	// RouteHome and RouteOpenAPI routes are served by the API itself, see
+	// ServeHome and ServeOpenAPI
+	RouteHome    RouteKind = "home"
+	RouteOpenAPI RouteKind = "openapi"
+)
+
+// Route describes a single route registered to a resource
+type Route struct {
+	Method string
+	// Pattern is the goji pattern of the route, i.e. "/articles/:id"
+	Pattern string
+	// Resource is the type of the resource serving the route, it is empty for
+	// routes served by the API itself
+	Resource string
+	// Relationship is the name of the relationship served by RouteRelated and
+	// RouteRelationship routes
+	Relationship string
+	Kind         RouteKind
+}
+
+/*
+Routes lists the routes served by the API itself, followed by the routes
+registered to each of the API's resources. Resources are listed in alphabetical
+order, all routes in registration order, so that the result is stable across
+calls. Patterns include the API's prefix.
+*/
+func (a *API) Routes() []Route {
+	routes := append([]Route{}, a.routes...)
+
+	for _, resourceType := range a.resourceTypes() {
+		for _, route := range a.Resources[resourceType].routes {
+			route.Pattern = a.resourcePath(resourceType, route.Pattern)
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+/*
+ServeHome registers a `GET /<prefix>` route that responds with a home document
+linking to the collection of every resource served by the API, so that clients
+can discover it:
+
+	{
+		"data": null,
+		"links": {"self": "/api"},
+		"meta": {"resources": {"articles": {"href": "/api/articles"}}}
+	}
+*/
+func (a *API) ServeHome() {
+	a.addRoute(Route{Method: get, Pattern: a.prefix, Kind: RouteHome})
+
+	a.Mux.HandleFunc(pat.Get(a.prefix), func(w http.ResponseWriter, r *http.Request) {
+		resources := map[string]homeLink{}
+		for resourceType := range a.Resources {
+			resources[resourceType] = homeLink{HREF: a.resourcePath(resourceType)}
+		}
+
+		document := a.config().New()
+		document.Status = http.StatusOK
+		document.Links = &jsh.Links{Self: jsh.NewLink(a.prefix)}
+		document.Meta = map[string]interface{}{"resources": resources}
+
+		a.sender()(w, r, document)
+	})
+}
+
+// homeLink is a link object, jsh.Link encodes links without meta as strings
+type homeLink struct {
+	HREF string `json:"href"`
+}
+
+// addRoute records a route served by the API itself, see Routes
+func (a *API) addRoute(route Route) {
+	a.routes = append(a.routes, route)
+}
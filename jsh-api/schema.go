@@ -0,0 +1,546 @@
+package jshapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+// AttributeType is the JSON type of an attribute's value
+type AttributeType string
+
+// Attribute types supported by a Schema, AttributeDateTime values are RFC 3339
+// strings
+const (
+	AttributeString   AttributeType = "string"
+	AttributeInteger  AttributeType = "integer"
+	AttributeNumber   AttributeType = "number"
+	AttributeBoolean  AttributeType = "boolean"
+	AttributeArray    AttributeType = "array"
+	AttributeObject   AttributeType = "object"
+	AttributeDateTime AttributeType = "date-time"
+)
+
+// AttributeSchema declares a single attribute of a resource
+type AttributeSchema struct {
+	Name     string
+	Type     AttributeType
+	Required bool
+	// ReadOnly attributes are rejected in requests, see AttributeRule
+	ReadOnly   bool
+	Sortable   bool
+	Filterable bool
+}
+
+// RelationshipSchema declares a relationship of a resource and the type of the
+// resources it links to
+type RelationshipSchema struct {
+	Name   string
+	Type   string
+	ToMany bool
+}
+
+/*
+Schema declares the attributes and relationships of a resource. Once set via
+Resource.SetSchema, request objects are validated against it and it decides
+which fields lists can be sorted and filtered by, as well as which fields can be
+requested as sparse fieldsets. A schema is built explicitly:
+
+	schema := &jshapi.Schema{}
+	schema.Attribute(jshapi.AttributeSchema{Name: "title", Type: jshapi.AttributeString, Required: true})
+	schema.ToOne("author", "people")
+
+Or from a struct, see SchemaFromStruct.
+*/
+type Schema struct {
+	Attributes    []AttributeSchema
+	Relationships []RelationshipSchema
+}
+
+// Attribute adds an attribute to the schema
+func (s *Schema) Attribute(attribute AttributeSchema) *Schema {
+	s.Attributes = append(s.Attributes, attribute)
+	return s
+}
+
+// ToOne adds a One-To-One relationship to resources of resourceType
+func (s *Schema) ToOne(name string, resourceType string) *Schema {
+	s.Relationships = append(s.Relationships, RelationshipSchema{Name: name, Type: resourceType})
+	return s
+}
+
+// ToMany adds a One-To-Many relationship to resources of resourceType
+func (s *Schema) ToMany(name string, resourceType string) *Schema {
+	s.Relationships = append(s.Relationships, RelationshipSchema{Name: name, Type: resourceType, ToMany: true})
+	return s
+}
+
+// FindAttribute returns the attribute with the provided name
+func (s *Schema) FindAttribute(name string) (AttributeSchema, bool) {
+	for _, attribute := range s.Attributes {
+		if attribute.Name == name {
+			return attribute, true
+		}
+	}
+
+	return AttributeSchema{}, false
+}
+
+// FindRelationship returns the relationship with the provided name
+func (s *Schema) FindRelationship(name string) (RelationshipSchema, bool) {
+	for _, relationship := range s.Relationships {
+		if relationship.Name == name {
+			return relationship, true
+		}
+	}
+
+	return RelationshipSchema{}, false
+}
+
+/*
+SchemaFromStruct builds the attributes of a schema from the exported fields of a
+struct, named after their json tags. Attribute types are derived from the field
+types, options are set via the jsh tag:
+
+	type Article struct {
+		ID      string    `json:"-"`
+		Title   string    `json:"title" valid:"required" jsh:"sortable,filterable"`
+		Created time.Time `json:"created" jsh:"readonly,sortable"`
+	}
+
+Fields tagged `jsh:"id"`, named ID, or skipped by encoding/json are left out, and
+`valid:"required"` marks an attribute as required as well. The fields of embedded
+structs are flattened the way encoding/json does. Relationships can be added to
+the result via ToOne and ToMany.
+*/
+func SchemaFromStruct(value interface{}) *Schema {
+	valueType := reflect.TypeOf(value)
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	if valueType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("jshapi: cannot build a schema from %s", valueType))
+	}
+
+	schema := &Schema{}
+
+	for _, field := range schemaFields(valueType) {
+		options := strings.Split(field.Tag.Get("jsh"), ",")
+		attribute := AttributeSchema{Name: field.name, Type: attributeType(field.Type)}
+
+		for _, option := range options {
+			switch option {
+			case "required":
+				attribute.Required = true
+			case "readonly":
+				attribute.ReadOnly = true
+			case "sortable":
+				attribute.Sortable = true
+			case "filterable":
+				attribute.Filterable = true
+			}
+		}
+
+		if strings.Contains(field.Tag.Get("valid"), "required") {
+			attribute.Required = true
+		}
+
+		schema.Attribute(attribute)
+	}
+
+	return schema
+}
+
+// schemaField is a struct field encoded as an attribute, along with how deeply
+// it is embedded
+type schemaField struct {
+	reflect.StructField
+	name   string
+	depth  int
+	tagged bool
+}
+
+/*
+schemaFields lists the fields of a struct that are encoded as attributes. The
+fields of embedded structs without a json name are promoted as encoding/json
+does: of several fields with the same name, the least nested one wins, then the
+one with a json name. Fields that are still ambiguous are left out.
+*/
+func schemaFields(structType reflect.Type) []schemaField {
+	candidates := []schemaField{}
+	collectSchemaFields(structType, 0, map[reflect.Type]bool{}, &candidates)
+
+	byName := map[string][]schemaField{}
+	for _, candidate := range candidates {
+		byName[candidate.name] = append(byName[candidate.name], candidate)
+	}
+
+	fields := []schemaField{}
+	for _, candidate := range candidates {
+		dominant, exists := dominantField(byName[candidate.name])
+		if exists && reflect.DeepEqual(dominant.Index, candidate.Index) {
+			fields = append(fields, candidate)
+		}
+	}
+
+	return fields
+}
+
+// dominantField picks the field encoding/json encodes out of several fields with
+// the same name, if any
+func dominantField(fields []schemaField) (schemaField, bool) {
+	depth := fields[0].depth
+	for _, field := range fields {
+		if field.depth < depth {
+			depth = field.depth
+		}
+	}
+
+	shallowest, tagged := []schemaField{}, []schemaField{}
+	for _, field := range fields {
+		if field.depth != depth {
+			continue
+		}
+
+		shallowest = append(shallowest, field)
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+
+	switch {
+	case len(shallowest) == 1:
+		return shallowest[0], true
+	case len(tagged) == 1:
+		return tagged[0], true
+	}
+
+	return schemaField{}, false
+}
+
+// collectSchemaFields appends the fields of structType, recursing into embedded
+// structs, visited guards against embedding cycles
+func collectSchemaFields(structType reflect.Type, depth int, visited map[reflect.Type]bool, fields *[]schemaField) {
+	if visited[structType] {
+		return
+	}
+	visited[structType] = true
+	defer func() { visited[structType] = false }()
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				start := len(*fields)
+				collectSchemaFields(embedded, depth+1, visited, fields)
+
+				for j := start; j < len(*fields); j++ {
+					(*fields)[j].Index = append([]int{i}, (*fields)[j].Index...)
+				}
+				continue
+			}
+		}
+
+		id := strings.Split(field.Tag.Get("jsh"), ",")[0] == "id"
+		if field.PkgPath != "" || field.Name == "ID" || name == "-" || id {
+			continue
+		}
+
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+
+		*fields = append(*fields, schemaField{StructField: field, name: name, depth: depth, tagged: tagged})
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// attributeType maps a Go type to the JSON type it is encoded as
+func attributeType(fieldType reflect.Type) AttributeType {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	if fieldType == timeType {
+		return AttributeDateTime
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		return AttributeString
+	case reflect.Bool:
+		return AttributeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return AttributeInteger
+	case reflect.Float32, reflect.Float64:
+		return AttributeNumber
+	case reflect.Slice, reflect.Array:
+		return AttributeArray
+	}
+
+	return AttributeObject
+}
+
+// SetSchema attaches a schema to the resource, read-only attributes are added to
+// the resource's AttributeRules
+func (res *Resource) SetSchema(schema *Schema) {
+	res.Schema = schema
+
+	for _, attribute := range schema.Attributes {
+		if !attribute.ReadOnly {
+			continue
+		}
+
+		rule := res.AttributeRules[attribute.Name]
+		rule.ReadOnly = true
+		res.AttributeRules[attribute.Name] = rule
+	}
+}
+
+/*
+validateSchema checks a request object against the resource's schema. Unknown
+attributes and relationships, values of the wrong type, linkage to resources of
+the wrong type, and missing required attributes of POST requests all result in
+a 422 for each offending field.
+*/
+func (res *Resource) validateSchema(r *http.Request, object *jsh.Object) jsh.ErrorType {
+	if res.Schema == nil {
+		return nil
+	}
+
+	attributes := map[string]json.RawMessage{}
+	if len(object.Attributes) > 0 {
+		err := json.Unmarshal(object.Attributes, &attributes)
+		if err != nil {
+			return jsh.InputError("Attributes must be an object", "attributes")
+		}
+	}
+
+	errors := jsh.ErrorList{}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		attribute, exists := res.Schema.FindAttribute(name)
+		if !exists {
+			errors = append(errors, jsh.InputError(fmt.Sprintf("Unknown attribute '%s'", name), name))
+			continue
+		}
+
+		if !attribute.accepts(attributes[name]) {
+			errors = append(errors, jsh.InputError(
+				fmt.Sprintf("Attribute '%s' must be of type %s", name, attribute.Type),
+				name,
+			))
+		}
+	}
+
+	if r.Method == post {
+		for _, attribute := range res.Schema.Attributes {
+			if _, exists := attributes[attribute.Name]; attribute.Required && !exists {
+				errors = append(errors, jsh.InputError(
+					fmt.Sprintf("Attribute '%s' is required", attribute.Name),
+					attribute.Name,
+				))
+			}
+		}
+	}
+
+	relationships := make([]string, 0, len(object.Relationships))
+	for name := range object.Relationships {
+		relationships = append(relationships, name)
+	}
+	sort.Strings(relationships)
+
+	for _, name := range relationships {
+		schema, exists := res.Schema.FindRelationship(name)
+		if !exists {
+			errors = append(errors, jsh.RelationshipError(fmt.Sprintf("Unknown relationship '%s'", name), name))
+			continue
+		}
+
+		relationship := object.Relationships[name]
+		if relationship == nil {
+			continue
+		}
+
+		if relationship.ToOne == schema.ToMany {
+			errors = append(errors, schema.shapeError())
+			continue
+		}
+
+		if !schema.accepts(relationship.Data) {
+			errors = append(errors, schema.typeError())
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+/*
+checkLinkage validates the linkage sent to a relationship endpoint against the
+resource's schema, responding with a 422 if it links to resources of the wrong
+type. Relationships the schema doesn't declare are left to storage.
+*/
+func (res *Resource) checkLinkage(name string, linkage jsh.ResourceLinkage) *jsh.Error {
+	if res.Schema == nil {
+		return nil
+	}
+
+	schema, exists := res.Schema.FindRelationship(name)
+	if !exists || schema.accepts(linkage) {
+		return nil
+	}
+
+	err := schema.typeError()
+	err.Source.Pointer = "/data"
+	return err
+}
+
+// accepts checks whether linkage only links to resources of the relationship's
+// type
+func (schema RelationshipSchema) accepts(linkage jsh.ResourceLinkage) bool {
+	for _, identifier := range linkage {
+		if identifier.Type != schema.Type {
+			return false
+		}
+	}
+
+	return true
+}
+
+// typeError is the 422 sent for linkage to resources of the wrong type
+func (schema RelationshipSchema) typeError() *jsh.Error {
+	return jsh.RelationshipError(
+		fmt.Sprintf("Relationship '%s' must link to resources of type '%s'", schema.Name, schema.Type),
+		schema.Name,
+	)
+}
+
+// shapeError is the 422 sent for a single identifier given to a to-many
+// relationship, or an array given to a to-one relationship
+func (schema RelationshipSchema) shapeError() *jsh.Error {
+	if schema.ToMany {
+		return jsh.RelationshipError(
+			fmt.Sprintf("Relationship '%s' must be set to an array of resource identifiers", schema.Name),
+			schema.Name,
+		)
+	}
+
+	return jsh.RelationshipError(
+		fmt.Sprintf("Relationship '%s' must be set to a single resource identifier or null", schema.Name),
+		schema.Name,
+	)
+}
+
+// accepts checks whether a raw JSON value matches the attribute's type, null is
+// accepted for attributes that aren't required
+func (attribute AttributeSchema) accepts(raw json.RawMessage) bool {
+	var value interface{}
+
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+
+	if decoder.Decode(&value) != nil {
+		return false
+	}
+
+	if value == nil {
+		return !attribute.Required
+	}
+
+	switch attribute.Type {
+	case AttributeString:
+		_, valid := value.(string)
+		return valid
+	case AttributeDateTime:
+		timestamp, valid := value.(string)
+		if !valid {
+			return false
+		}
+		_, err := time.Parse(time.RFC3339Nano, timestamp)
+		return err == nil
+	case AttributeInteger:
+		number, valid := value.(json.Number)
+		if !valid {
+			return false
+		}
+		_, err := number.Int64()
+		return err == nil
+	case AttributeNumber:
+		_, valid := value.(json.Number)
+		return valid
+	case AttributeBoolean:
+		_, valid := value.(bool)
+		return valid
+	case AttributeArray:
+		_, valid := value.([]interface{})
+		return valid
+	case AttributeObject:
+		_, valid := value.(map[string]interface{})
+		return valid
+	}
+
+	return true
+}
+
+// sortable checks whether lists of the resource can be sorted by field
+func (res *Resource) sortable(field string) bool {
+	if !allowed(res.SortFields, field) {
+		return false
+	}
+
+	if res.Schema == nil || field == "id" {
+		return true
+	}
+
+	attribute, exists := res.Schema.FindAttribute(field)
+	return exists && attribute.Sortable
+}
+
+// filterable checks whether lists of the resource can be filtered by field
+func (res *Resource) filterable(field string) bool {
+	if !allowed(res.FilterFields, field) {
+		return false
+	}
+
+	if res.Schema == nil || field == "id" {
+		return true
+	}
+
+	attribute, exists := res.Schema.FindAttribute(field)
+	return exists && attribute.Filterable
+}
+
+// hasField checks whether a sparse fieldset may contain field
+func (s *Schema) hasField(field string) bool {
+	_, isAttribute := s.FindAttribute(field)
+	_, isRelationship := s.FindRelationship(field)
+
+	return isAttribute || isRelationship
+}
@@ -0,0 +1,143 @@
+package jshapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store/memory"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type schemaArticle struct {
+	ID      string    `json:"-"`
+	Title   string    `json:"title" valid:"required" jsh:"sortable,filterable"`
+	Views   int       `json:"views,omitempty" jsh:"sortable"`
+	Tags    []string  `json:"tags,omitempty"`
+	Created time.Time `json:"created" jsh:"readonly"`
+	secret  string
+}
+
+type schemaTimestamps struct {
+	Created time.Time `json:"created" jsh:"readonly"`
+	Updated time.Time `json:"updated"`
+}
+
+type schemaComment struct {
+	schemaTimestamps
+	ID      string `json:"-"`
+	Body    string `json:"body"`
+	Updated string `json:"updated"`
+}
+
+func TestSchema(t *testing.T) {
+
+	Convey("Schema Tests", t, func() {
+
+		Convey("->SchemaFromStruct()", func() {
+			schema := SchemaFromStruct(&schemaArticle{})
+
+			So(schema.Attributes, ShouldResemble, []AttributeSchema{
+				{Name: "title", Type: AttributeString, Required: true, Sortable: true, Filterable: true},
+				{Name: "views", Type: AttributeInteger, Sortable: true},
+				{Name: "tags", Type: AttributeArray},
+				{Name: "created", Type: AttributeDateTime, ReadOnly: true},
+			})
+		})
+
+		Convey("->SchemaFromStruct() should promote the fields of embedded structs", func() {
+			schema := SchemaFromStruct(schemaComment{})
+
+			So(schema.Attributes, ShouldResemble, []AttributeSchema{
+				{Name: "created", Type: AttributeDateTime, ReadOnly: true},
+				{Name: "body", Type: AttributeString},
+				{Name: "updated", Type: AttributeString},
+			})
+		})
+
+		resource := NewCRUDResource("articles", memory.New("articles"))
+		resource.SetSchema(SchemaFromStruct(schemaArticle{}).ToOne("author", "people"))
+
+		api := New("")
+		api.Add(resource)
+
+		server := httptest.NewServer(api)
+		defer server.Close()
+
+		post := func(attributes map[string]interface{}, relationships map[string]*jsh.Relationship) (*jsh.Document, *http.Response) {
+			object, _ := jsh.NewObject("", "articles", attributes)
+			object.Relationships = relationships
+
+			doc, resp, err := jsc.Post(server.URL, object)
+			So(err, ShouldBeNil)
+			return doc, resp
+		}
+
+		Convey("should accept valid objects", func() {
+			_, resp := post(map[string]interface{}{"title": "Schemas", "views": 3}, map[string]*jsh.Relationship{
+				"author": {Data: jsh.ResourceLinkage{{Type: "people", ID: "1"}}, ToOne: true},
+			})
+			So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+		})
+
+		Convey("should reject objects violating the schema", func() {
+			doc, resp := post(map[string]interface{}{"views": 1.5, "color": "red"}, map[string]*jsh.Relationship{
+				"author": {Data: jsh.ResourceLinkage{{Type: "articles", ID: "1"}}, ToOne: true},
+				"editor": {Data: jsh.ResourceLinkage{{Type: "people", ID: "1"}}, ToOne: true},
+			})
+			So(resp.StatusCode, ShouldEqual, 422)
+
+			pointers := []string{}
+			for _, err := range doc.Errors {
+				pointers = append(pointers, err.Source.Pointer)
+			}
+			So(pointers, ShouldResemble, []string{
+				"/data/attributes/color",
+				"/data/attributes/views",
+				"/data/attributes/title",
+				"/data/relationships/author",
+				"/data/relationships/editor",
+			})
+		})
+
+		Convey("should reject relationships of the wrong shape", func() {
+			doc, resp := post(map[string]interface{}{"title": "Schemas"}, map[string]*jsh.Relationship{
+				"author": {Data: jsh.ResourceLinkage{{Type: "people", ID: "1"}}},
+			})
+			So(resp.StatusCode, ShouldEqual, 422)
+			So(doc.Errors[0].Source.Pointer, ShouldEqual, "/data/relationships/author")
+		})
+
+		Convey("should reject writes to read-only attributes", func() {
+			_, resp := post(map[string]interface{}{"title": "Schemas", "created": "2016-01-01T00:00:00Z"}, nil)
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("should restrict list queries", func() {
+			parse := func(rawQuery string) *jsh.Error {
+				request := httptest.NewRequest("GET", "/articles?"+rawQuery, nil)
+				_, err := resource.parseQuery(request)
+				return err
+			}
+
+			So(parse("sort=-views,title&filter[title]=Schemas&fields[articles]=title,author"), ShouldBeNil)
+			So(parse("sort=tags"), ShouldNotBeNil)
+			So(parse("filter[views]=1"), ShouldNotBeNil)
+			So(parse("fields[articles]=body").Source.Parameter, ShouldEqual, "fields[articles]")
+		})
+
+		Convey("should validate sparse fieldsets when fetching a resource", func() {
+			request, err := jsc.FetchRequest(server.URL, "articles", "1")
+			So(err, ShouldBeNil)
+			request.URL.RawQuery = "fields[articles]=body"
+
+			doc, resp, err := jsc.Do(request, jsh.ObjectMode)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+			So(doc.Errors[0].Source.Parameter, ShouldEqual, "fields[articles]")
+		})
+	})
+}
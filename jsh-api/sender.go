@@ -15,7 +15,8 @@ type Sender func(http.ResponseWriter, *http.Request, jsh.Sendable)
 
 /*
 DefaultSender is the default sender that will log 5XX errors that it encounters
-in the process of sending a response.
+in the process of sending a response. Responses are sent using the jsh.Config of
+the API serving the request.
 */
 func DefaultSender(logger std.Logger) Sender {
 	return func(w http.ResponseWriter, r *http.Request, sendable jsh.Sendable) {
@@ -24,7 +25,7 @@ func DefaultSender(logger std.Logger) Sender {
 			logger.Printf("Returning ISE: %s\n", sendableError.Error())
 		}
 
-		sendError := jsh.Send(w, r, sendable)
+		sendError := apiFromRequest(r).config().Send(w, r, sendable)
 		if sendError != nil && sendError.Status >= 500 {
 			logger.Printf("Error sending response: %s\n", sendError.Error())
 		}
@@ -0,0 +1,634 @@
+/*
+Package memory is a concurrency-safe, in-memory implementation of store.CRUD. It
+is meant for integration tests and local environments that run without a
+database:
+
+	users := memory.New("users", "email")
+	api.Add(jshapi.NewCRUDResource("users", users))
+
+Besides store.CRUD, a Store implements store.QueryList, store.VersionedUpdate and
+store.VersionedDelete, so lists can be filtered, sorted and paginated and updates
+can be made conditional.
+*/
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+/*
+Store holds the objects of a single resource type. Objects are copied on the way
+in and out, so callers are free to modify what they pass and receive.
+*/
+type Store struct {
+	// Type is the resource type of all stored objects
+	Type string
+	// Unique lists the attributes whose values must be unique across objects,
+	// duplicates are rejected with a 409 Conflict
+	Unique []string
+
+	lock    sync.RWMutex
+	records map[string]*record
+	// unique maps the encoded value of each unique attribute to the id of the
+	// object holding it, see uniqueKey
+	unique map[string]map[string]string
+	// order holds the ids in creation order, the default order of lists
+	order  []string
+	lastID int
+}
+
+// New creates an empty Store for a resource type
+func New(resourceType string, unique ...string) *Store {
+	return &Store{
+		Type:    resourceType,
+		Unique:  unique,
+		records: map[string]*record{},
+		unique:  map[string]map[string]string{},
+		order:   []string{},
+	}
+}
+
+/*
+Save stores a new object. An ID is assigned unless the object already has one,
+in which case it must not be taken. The object's version starts at "1".
+*/
+func (s *Store) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if object.Type != s.Type {
+		return nil, jsh.Conflict(
+			fmt.Sprintf("Type '%s' does not match resource type '%s'", object.Type, s.Type),
+			"",
+		)
+	}
+
+	saved := copyObject(object)
+
+	if saved.ID == "" {
+		saved.ID = s.nextID()
+	} else if _, exists := s.records[saved.ID]; exists {
+		return nil, jsh.Conflict(
+			fmt.Sprintf("A resource of type '%s' already exists for ID: %s", s.Type, saved.ID),
+			"",
+		)
+	}
+
+	if len(saved.Attributes) == 0 {
+		saved.Attributes = json.RawMessage("{}")
+	}
+
+	record, err := newRecord(saved)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkUnique(record)
+	if err != nil {
+		return nil, err
+	}
+
+	setVersion(saved, 1)
+
+	s.put(record)
+	s.order = append(s.order, saved.ID)
+
+	return copyObject(saved), nil
+}
+
+// Get returns the object with the provided id, or a 404 if there isn't one
+func (s *Store) Get(ctx context.Context, id string) (*jsh.Object, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	record, exists := s.records[id]
+	if !exists {
+		return nil, jsh.NotFound(s.Type, id)
+	}
+
+	return copyObject(record.object), nil
+}
+
+// List returns all objects in the order they were created
+func (s *Store) List(ctx context.Context) (jsh.List, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	list := jsh.List{}
+	for _, id := range s.order {
+		list = append(list, copyObject(s.records[id].object))
+	}
+
+	return list, nil
+}
+
+/*
+ListQuery lists the objects matching the query, see store.Query:
+
+	filter[name]  matches objects whose attribute equals one of the comma
+	              separated values, "id" matches ids, the same goes for Scope
+	sort          orders by attribute values, ties keep creation order
+	page          selects a page by number, or by a cursor returned in a
+	              previous result
+	fields[type]  trims the attributes of the returned objects
+*/
+func (s *Store) ListQuery(ctx context.Context, query *store.Query) (*store.ListResult, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	matches := []*record{}
+	for _, id := range s.order {
+		record := s.records[id]
+		if record.matches(query.Filter) && record.matches(query.Scope) {
+			matches = append(matches, record)
+		}
+	}
+
+	if len(query.Sort) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].less(matches[j], query.Sort)
+		})
+	}
+
+	result := &store.ListResult{Objects: jsh.List{}, Total: len(matches), TotalKnown: true}
+
+	start, end := 0, len(matches)
+	if query.Page.Size > 0 {
+		if query.Page.Cursor != "" {
+			offset, err := strconv.Atoi(query.Page.Cursor)
+			if err != nil || offset < 0 {
+				return nil, jsh.ParameterError("Invalid page cursor", "page[cursor]")
+			}
+			start = offset
+
+			if start > 0 {
+				previous := start - query.Page.Size
+				if previous < 0 {
+					previous = 0
+				}
+				result.PrevCursor = strconv.Itoa(previous)
+			}
+		} else if query.Page.Number > 0 {
+			start = (query.Page.Number - 1) * query.Page.Size
+		}
+
+		if start > len(matches) {
+			start = len(matches)
+		}
+
+		end = start + query.Page.Size
+		if end > len(matches) {
+			end = len(matches)
+		}
+
+		if query.Page.Cursor != "" && end < len(matches) {
+			result.NextCursor = strconv.Itoa(end)
+		}
+	}
+
+	fields, sparse := query.Fields[s.Type]
+	for _, record := range matches[start:end] {
+		object := copyObject(record.object)
+
+		if sparse {
+			err := trimAttributes(object, record.attributes, fields)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result.Objects = append(result.Objects, object)
+	}
+
+	return result, nil
+}
+
+// Update modifies an existing object, see UpdateVersion
+func (s *Store) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	return s.UpdateVersion(ctx, object, "")
+}
+
+/*
+UpdateVersion merges the object's attributes and relationships into the stored
+object, leaving those that aren't provided untouched, and increments its version.
+If a version other than "" or "*" is provided, it must match the stored one.
+*/
+func (s *Store) UpdateVersion(ctx context.Context, object *jsh.Object, version string) (*jsh.Object, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stored, exists := s.records[object.ID]
+	if !exists {
+		return nil, jsh.NotFound(s.Type, object.ID)
+	}
+
+	existing := stored.object
+	if !versionMatches(existing, version) {
+		return nil, store.ErrVersionMismatch
+	}
+
+	updated := copyObject(existing)
+
+	if len(object.Attributes) > 0 {
+		attributes, err := mergeAttributes(existing.Attributes, object.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		updated.Attributes = attributes
+	}
+
+	for name, relationship := range object.Relationships {
+		if updated.Relationships == nil {
+			updated.Relationships = map[string]*jsh.Relationship{}
+		}
+		updated.Relationships[name] = copyRelationship(relationship)
+	}
+
+	record, err := newRecord(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkUnique(record)
+	if err != nil {
+		return nil, err
+	}
+
+	current, _ := strconv.Atoi(existing.Version())
+	setVersion(updated, current+1)
+
+	s.put(record)
+	return copyObject(updated), nil
+}
+
+// Delete removes an object, see DeleteVersion
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.DeleteVersion(ctx, id, "")
+}
+
+// DeleteVersion removes an object if it matches the expected version, see
+// UpdateVersion
+func (s *Store) DeleteVersion(ctx context.Context, id string, version string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	existing, exists := s.records[id]
+	if !exists {
+		return jsh.NotFound(s.Type, id)
+	}
+
+	if !versionMatches(existing.object, version) {
+		return store.ErrVersionMismatch
+	}
+
+	s.remove(id)
+	for i, orderedID := range s.order {
+		if orderedID == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// nextID returns the next unused numeric id
+func (s *Store) nextID() string {
+	for {
+		s.lastID++
+
+		id := strconv.Itoa(s.lastID)
+		if _, exists := s.records[id]; !exists {
+			return id
+		}
+	}
+}
+
+// checkUnique ensures that no other object shares the value of a unique attribute
+func (s *Store) checkUnique(candidate *record) error {
+	for _, attribute := range s.Unique {
+		key, isSet := uniqueKey(candidate.attributes[attribute])
+		if !isSet {
+			continue
+		}
+
+		id, taken := s.uniqueIndex(attribute)[key]
+		if taken && id != candidate.object.ID {
+			return jsh.Conflict(
+				fmt.Sprintf("Attribute '%s' must be unique", attribute),
+				attribute,
+			)
+		}
+	}
+
+	return nil
+}
+
+// uniqueIndex returns the index of a unique attribute, building it from the
+// stored records if the attribute was added to Unique after they were saved
+func (s *Store) uniqueIndex(attribute string) map[string]string {
+	index, exists := s.unique[attribute]
+	if exists {
+		return index
+	}
+
+	index = map[string]string{}
+	for id, record := range s.records {
+		if key, isSet := uniqueKey(record.attributes[attribute]); isSet {
+			index[key] = id
+		}
+	}
+
+	s.unique[attribute] = index
+	return index
+}
+
+// put stores a record, replacing the index entries of the one it updates
+func (s *Store) put(record *record) {
+	s.remove(record.object.ID)
+
+	for _, attribute := range s.Unique {
+		if key, isSet := uniqueKey(record.attributes[attribute]); isSet {
+			s.uniqueIndex(attribute)[key] = record.object.ID
+		}
+	}
+
+	s.records[record.object.ID] = record
+}
+
+// remove deletes a record along with its index entries, the order is left
+// untouched
+func (s *Store) remove(id string) {
+	existing, exists := s.records[id]
+	if !exists {
+		return
+	}
+
+	for attribute, index := range s.unique {
+		key, isSet := uniqueKey(existing.attributes[attribute])
+		if isSet && index[key] == id {
+			delete(index, key)
+		}
+	}
+
+	delete(s.records, id)
+}
+
+/*
+uniqueKey encodes an attribute value for the unique index. Values are encoded as
+JSON so that only values of the same type can collide, i.e. 1 and "1" are
+distinct. Returns false for unset and null values, which never conflict.
+*/
+func uniqueKey(value interface{}) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+
+	return string(raw), true
+}
+
+func versionMatches(object *jsh.Object, version string) bool {
+	return version == "" || version == "*" || version == object.Version()
+}
+
+func setVersion(object *jsh.Object, version int) {
+	if object.Meta == nil {
+		object.Meta = map[string]interface{}{}
+	}
+
+	object.Meta[jsh.MetaVersion] = strconv.Itoa(version)
+}
+
+// mergeAttributes overwrites the existing attributes with the updated ones
+func mergeAttributes(existing json.RawMessage, updated json.RawMessage) (json.RawMessage, error) {
+	merged := map[string]json.RawMessage{}
+
+	if len(existing) > 0 {
+		err := json.Unmarshal(existing, &merged)
+		if err != nil {
+			return nil, jsh.ISE(fmt.Sprintf("Error decoding stored attributes: %s", err))
+		}
+	}
+
+	changes := map[string]json.RawMessage{}
+	err := json.Unmarshal(updated, &changes)
+	if err != nil {
+		return nil, jsh.InputError("Attributes must be an object", "attributes")
+	}
+
+	for name, value := range changes {
+		merged[name] = value
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, jsh.ISE(fmt.Sprintf("Error encoding attributes: %s", err))
+	}
+
+	return raw, nil
+}
+
+// trimAttributes only keeps the requested fields of an object's attributes
+func trimAttributes(object *jsh.Object, attributes map[string]interface{}, fields []string) error {
+	trimmed := map[string]interface{}{}
+	for _, field := range fields {
+		if value, exists := attributes[field]; exists {
+			trimmed[field] = value
+		}
+	}
+
+	raw, err := json.Marshal(trimmed)
+	if err != nil {
+		return jsh.ISE(fmt.Sprintf("Error encoding attributes: %s", err))
+	}
+
+	object.Attributes = raw
+	return nil
+}
+
+// record is a stored object along with its decoded attributes, used to filter
+// and sort
+type record struct {
+	object     *jsh.Object
+	attributes map[string]interface{}
+}
+
+func newRecord(object *jsh.Object) (*record, error) {
+	attributes := map[string]interface{}{}
+
+	if len(object.Attributes) > 0 {
+		err := json.Unmarshal(object.Attributes, &attributes)
+		if err != nil {
+			return nil, jsh.ISE(fmt.Sprintf("Error decoding stored attributes: %s", err))
+		}
+	}
+
+	return &record{object: object, attributes: attributes}, nil
+}
+
+// value returns an attribute, "id" resolves to the object's id
+func (r *record) value(field string) interface{} {
+	if field == "id" {
+		return r.object.ID
+	}
+
+	return r.attributes[field]
+}
+
+// matches checks that every filter matches one of its comma separated values
+func (r *record) matches(filter map[string]string) bool {
+	for field, values := range filter {
+		value := fmt.Sprintf("%v", r.value(field))
+
+		matched := false
+		for _, candidate := range strings.Split(values, ",") {
+			if candidate == value {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *record) less(other *record, sortFields []store.SortField) bool {
+	for _, field := range sortFields {
+		comparison := compare(r.value(field.Field), other.value(field.Field))
+		if comparison == 0 {
+			continue
+		}
+
+		if field.Descending {
+			return comparison > 0
+		}
+		return comparison < 0
+	}
+
+	return false
+}
+
+/*
+compare orders decoded JSON values. Values of differing types are never equal,
+they are ordered by type: null, booleans, numbers, strings, then arrays and
+objects, which are compared by their JSON encoding.
+*/
+func compare(a interface{}, b interface{}) int {
+	rankA, rankB := typeRank(a), typeRank(b)
+	if rankA != rankB {
+		if rankA < rankB {
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		b := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+
+	encodedA, _ := json.Marshal(a)
+	encodedB, _ := json.Marshal(b)
+	return strings.Compare(string(encodedA), string(encodedB))
+}
+
+// typeRank orders the types of decoded JSON values, see compare
+func typeRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+
+	return 4
+}
+
+func copyObject(object *jsh.Object) *jsh.Object {
+	copied := &jsh.Object{
+		Type: object.Type,
+		ID:   object.ID,
+	}
+
+	if object.Attributes != nil {
+		copied.Attributes = append(json.RawMessage{}, object.Attributes...)
+	}
+
+	if object.Links != nil {
+		copied.Links = map[string]*jsh.Link{}
+		for name, link := range object.Links {
+			copied.Links[name] = link
+		}
+	}
+
+	if object.Relationships != nil {
+		copied.Relationships = map[string]*jsh.Relationship{}
+		for name, relationship := range object.Relationships {
+			copied.Relationships[name] = copyRelationship(relationship)
+		}
+	}
+
+	if object.Meta != nil {
+		copied.Meta = map[string]interface{}{}
+		for key, value := range object.Meta {
+			copied.Meta[key] = value
+		}
+	}
+
+	return copied
+}
+
+func copyRelationship(relationship *jsh.Relationship) *jsh.Relationship {
+	if relationship == nil {
+		return nil
+	}
+
+	copied := *relationship
+	if relationship.Data != nil {
+		copied.Data = append(jsh.ResourceLinkage{}, relationship.Data...)
+	}
+
+	return &copied
+}
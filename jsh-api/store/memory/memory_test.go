@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type user struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Age   int    `json:"age,omitempty"`
+}
+
+func TestStore(t *testing.T) {
+
+	ctx := context.Background()
+
+	newUser := func(id string, attributes user) *jsh.Object {
+		object, err := jsh.NewObject(id, "users", attributes)
+		So(err, ShouldBeNil)
+		return object
+	}
+
+	Convey("Memory Store Tests", t, func() {
+
+		users := New("users", "email")
+
+		Convey("->Save()", func() {
+
+			Convey("should assign IDs and versions", func() {
+				first, err := users.Save(ctx, newUser("", user{Name: "Ann"}))
+				So(err, ShouldBeNil)
+				So(first.ID, ShouldEqual, "1")
+				So(first.Version(), ShouldEqual, "1")
+
+				second, err := users.Save(ctx, newUser("", user{Name: "Bob"}))
+				So(err, ShouldBeNil)
+				So(second.ID, ShouldEqual, "2")
+			})
+
+			Convey("should reject duplicate IDs, types and unique attributes", func() {
+				_, err := users.Save(ctx, newUser("7", user{Name: "Ann", Email: "ann@example.com"}))
+				So(err, ShouldBeNil)
+
+				_, err = users.Save(ctx, newUser("7", user{Name: "Bob"}))
+				So(err.(*jsh.Error).Status, ShouldEqual, http.StatusConflict)
+
+				_, err = users.Save(ctx, newUser("", user{Name: "Bob", Email: "ann@example.com"}))
+				So(err.(*jsh.Error).Status, ShouldEqual, http.StatusConflict)
+				So(err.(*jsh.Error).Source.Pointer, ShouldEqual, "/data/attributes/email")
+
+				other, _ := jsh.NewObject("", "posts", user{Name: "Bob"})
+				_, err = users.Save(ctx, other)
+				So(err.(*jsh.Error).Status, ShouldEqual, http.StatusConflict)
+			})
+
+			Convey("should only treat values of the same type as duplicates", func() {
+				codes := New("codes", "code")
+
+				for _, code := range []interface{}{1, "1", true} {
+					object, _ := jsh.NewObject("", "codes", map[string]interface{}{"code": code})
+					_, err := codes.Save(ctx, object)
+					So(err, ShouldBeNil)
+				}
+
+				object, _ := jsh.NewObject("", "codes", map[string]interface{}{"code": 1.0})
+				_, err := codes.Save(ctx, object)
+				So(err.(*jsh.Error).Status, ShouldEqual, http.StatusConflict)
+			})
+
+			Convey("should release unique values of updated and deleted objects", func() {
+				saved, err := users.Save(ctx, newUser("", user{Name: "Ann", Email: "ann@example.com"}))
+				So(err, ShouldBeNil)
+
+				_, err = users.Update(ctx, newUser(saved.ID, user{Email: "ann@example.org"}))
+				So(err, ShouldBeNil)
+
+				other, err := users.Save(ctx, newUser("", user{Name: "Bob", Email: "ann@example.com"}))
+				So(err, ShouldBeNil)
+
+				So(users.Delete(ctx, saved.ID), ShouldBeNil)
+
+				_, err = users.Update(ctx, newUser(other.ID, user{Email: "ann@example.org"}))
+				So(err, ShouldBeNil)
+			})
+
+			Convey("should copy stored objects", func() {
+				saved, err := users.Save(ctx, newUser("", user{Name: "Ann"}))
+				So(err, ShouldBeNil)
+
+				saved.Attributes[2] = 'X'
+
+				stored, err := users.Get(ctx, saved.ID)
+				So(err, ShouldBeNil)
+
+				attributes := user{}
+				So(stored.Unmarshal("users", &attributes), ShouldBeNil)
+				So(attributes.Name, ShouldEqual, "Ann")
+			})
+		})
+
+		Convey("->Get()", func() {
+
+			Convey("should 404 for missing objects", func() {
+				_, err := users.Get(ctx, "9")
+				So(err.(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("->Update()", func() {
+			saved, err := users.Save(ctx, newUser("", user{Name: "Ann", Email: "ann@example.com"}))
+			So(err, ShouldBeNil)
+
+			Convey("should merge attributes and increment the version", func() {
+				patch := &jsh.Object{ID: saved.ID, Type: "users", Attributes: []byte(`{"age": 30}`)}
+
+				updated, err := users.Update(ctx, patch)
+				So(err, ShouldBeNil)
+				So(updated.Version(), ShouldEqual, "2")
+
+				attributes := user{}
+				So(updated.Unmarshal("users", &attributes), ShouldBeNil)
+				So(attributes, ShouldResemble, user{Name: "Ann", Email: "ann@example.com", Age: 30})
+			})
+
+			Convey("should reject stale versions", func() {
+				patch := &jsh.Object{ID: saved.ID, Type: "users", Attributes: []byte(`{"age": 30}`)}
+
+				_, err := users.UpdateVersion(ctx, patch, "2")
+				So(errors.Is(err, store.ErrVersionMismatch), ShouldBeTrue)
+
+				_, err = users.UpdateVersion(ctx, patch, "1")
+				So(err, ShouldBeNil)
+			})
+
+			Convey("should persist relationships", func() {
+				patch := &jsh.Object{
+					ID:   saved.ID,
+					Type: "users",
+					Relationships: map[string]*jsh.Relationship{
+						"company": {Data: jsh.ResourceLinkage{{Type: "companies", ID: "3"}}, ToOne: true},
+					},
+				}
+
+				_, err := users.Update(ctx, patch)
+				So(err, ShouldBeNil)
+
+				stored, err := users.Get(ctx, saved.ID)
+				So(err, ShouldBeNil)
+				So(stored.Relationships["company"].Data[0].ID, ShouldEqual, "3")
+			})
+
+			Convey("should 404 for missing objects", func() {
+				_, err := users.Update(ctx, newUser("9", user{Name: "Bob"}))
+				So(err.(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
+		Convey("->Delete()", func() {
+			saved, err := users.Save(ctx, newUser("", user{Name: "Ann"}))
+			So(err, ShouldBeNil)
+
+			So(users.DeleteVersion(ctx, saved.ID, "2"), ShouldEqual, store.ErrVersionMismatch)
+			So(users.Delete(ctx, saved.ID), ShouldBeNil)
+			So(users.Delete(ctx, saved.ID).(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+
+			list, err := users.List(ctx)
+			So(err, ShouldBeNil)
+			So(list, ShouldBeEmpty)
+		})
+
+		Convey("->ListQuery()", func() {
+			for _, attributes := range []user{
+				{Name: "Cid", Age: 40},
+				{Name: "Ann", Age: 30},
+				{Name: "Bob", Age: 30},
+				{Name: "Dee", Age: 20},
+			} {
+				_, err := users.Save(ctx, newUser("", attributes))
+				So(err, ShouldBeNil)
+			}
+
+			names := func(list jsh.List) []string {
+				result := []string{}
+				for _, object := range list {
+					attributes := user{}
+					So(object.Unmarshal("users", &attributes), ShouldBeNil)
+					result = append(result, attributes.Name)
+				}
+				return result
+			}
+
+			Convey("should filter and sort", func() {
+				result, err := users.ListQuery(ctx, &store.Query{
+					Filter: map[string]string{"age": "30,40"},
+					Sort:   []store.SortField{{Field: "age", Descending: true}, {Field: "name"}},
+				})
+				So(err, ShouldBeNil)
+				So(result.Total, ShouldEqual, 3)
+				So(names(result.Objects), ShouldResemble, []string{"Cid", "Ann", "Bob"})
+			})
+
+			Convey("should paginate by number", func() {
+				result, err := users.ListQuery(ctx, &store.Query{Page: store.Page{Number: 2, Size: 3}})
+				So(err, ShouldBeNil)
+				So(result.Total, ShouldEqual, 4)
+				So(names(result.Objects), ShouldResemble, []string{"Dee"})
+			})
+
+			Convey("should paginate by cursor", func() {
+				result, err := users.ListQuery(ctx, &store.Query{Page: store.Page{Size: 2, Cursor: "0"}})
+				So(err, ShouldBeNil)
+				So(names(result.Objects), ShouldResemble, []string{"Cid", "Ann"})
+				So(result.NextCursor, ShouldEqual, "2")
+
+				result, err = users.ListQuery(ctx, &store.Query{Page: store.Page{Size: 2, Cursor: result.NextCursor}})
+				So(err, ShouldBeNil)
+				So(names(result.Objects), ShouldResemble, []string{"Bob", "Dee"})
+				So(result.NextCursor, ShouldEqual, "")
+				So(result.PrevCursor, ShouldEqual, "0")
+			})
+
+			Convey("should apply sparse fieldsets", func() {
+				result, err := users.ListQuery(ctx, &store.Query{
+					Fields: map[string][]string{"users": {"name"}},
+				})
+				So(err, ShouldBeNil)
+				So(string(result.Objects[0].Attributes), ShouldEqual, `{"name":"Cid"}`)
+			})
+		})
+
+		Convey("should be safe for concurrent use", func() {
+			group := sync.WaitGroup{}
+			for i := 0; i < 20; i++ {
+				group.Add(1)
+				go func() {
+					defer group.Done()
+					users.Save(ctx, &jsh.Object{Type: "users"})
+					users.List(ctx)
+				}()
+			}
+			group.Wait()
+
+			list, err := users.List(ctx)
+			So(err, ShouldBeNil)
+			So(len(list), ShouldEqual, 20)
+		})
+	})
+}
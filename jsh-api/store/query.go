@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+
+	"github.com/derekdowling/go-json-spec-handler"
+)
+
+/*
+Query holds the parsed and validated query parameters of a list request:
+
+	GET /articles?include=author&fields[articles]=title&sort=-created&filter[author]=9&page[number]=2&page[size]=10
+*/
+type Query struct {
+	// Include is the tree of relationships requested via "include"
+	Include jsh.Include
+	// Fields are the sparse fieldsets requested via "fields[type]", keyed by type
+	Fields map[string][]string
+	// Sort is the requested sort order, in order of precedence
+	Sort []SortField
+	// Filter holds the "filter[name]" values keyed by name
+	Filter map[string]string
+	// Scope holds filters set by the resource's authorization policy. Storage
+	// must apply them in addition to Filter, they are never set by clients.
+	Scope map[string]string
+	// Page is the requested page of results
+	Page Page
+}
+
+// SortField is a single field of a "sort" query parameter
+type SortField struct {
+	Field string
+	// Descending is true for fields prefixed with "-"
+	Descending bool
+}
+
+/*
+Page is the requested page of results. Pages are either selected by Number, or
+by a Cursor that storage returned in a previous ListResult. A Size of 0 means
+that all results are requested.
+*/
+type Page struct {
+	Number int
+	Size   int
+	Cursor string
+}
+
+/*
+ListResult is returned by ListWithQuery storage. Total is the number of objects
+matching the query's filters regardless of pagination, it is only used when
+TotalKnown is set so that an empty result can report a total of 0. NextCursor and
+PrevCursor are optional, if set they are used to build the "next" and "prev"
+pagination links.
+*/
+type ListResult struct {
+	Objects    jsh.List
+	Total      int
+	TotalKnown bool
+	NextCursor string
+	PrevCursor string
+}
+
+// ListWithQuery lists the instances of a resource matching a query
+type ListWithQuery func(ctx context.Context, query *Query) (*ListResult, error)
+
+// QueryList can be implemented by CRUD storage to receive the query of list
+// requests, see ListWithQuery
+type QueryList interface {
+	ListQuery(ctx context.Context, query *Query) (*ListResult, error)
+}
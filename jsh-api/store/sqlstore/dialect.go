@@ -0,0 +1,101 @@
+package sqlstore
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Dialect covers the differences between the SQL databases supported by a Store.
+Unique violations are detected by the driver's error message so that sqlstore
+doesn't depend on any particular driver.
+*/
+type Dialect interface {
+	// Placeholder returns the bind parameter for the n-th argument, starting at 1
+	Placeholder(n int) string
+	// Quote quotes an identifier such as a table or column name
+	Quote(identifier string) string
+	// Returning is true if inserted ids are read via "RETURNING", otherwise
+	// sql.Result.LastInsertId is used
+	Returning() bool
+	// IsUniqueViolation checks whether err was caused by a unique constraint
+	IsUniqueViolation(err error) bool
+}
+
+var (
+	// SQLite is the Dialect for SQLite drivers such as mattn/go-sqlite3 and
+	// modernc.org/sqlite
+	SQLite Dialect = sqliteDialect{}
+	// Postgres is the Dialect for PostgreSQL drivers such as lib/pq and pgx
+	Postgres Dialect = postgresDialect{}
+	// MySQL is the Dialect for go-sql-driver/mysql
+	MySQL Dialect = mysqlDialect{}
+)
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqliteDialect) Quote(identifier string) string {
+	return quote(identifier, `"`)
+}
+
+func (sqliteDialect) Returning() bool {
+	return false
+}
+
+func (sqliteDialect) IsUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgresDialect) Quote(identifier string) string {
+	return quote(identifier, `"`)
+}
+
+func (postgresDialect) Returning() bool {
+	return true
+}
+
+func (postgresDialect) IsUniqueViolation(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "23505") ||
+		strings.Contains(message, "duplicate key value violates unique constraint")
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return quote(identifier, "`")
+}
+
+func (mysqlDialect) Returning() bool {
+	return false
+}
+
+func (mysqlDialect) IsUniqueViolation(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "Error 1062") || strings.Contains(message, "Duplicate entry")
+}
+
+// quote wraps an identifier in quotes, escaping any it contains. Qualified names
+// like "users.id" are quoted per segment.
+func quote(identifier string, mark string) string {
+	segments := strings.Split(identifier, ".")
+	for i, segment := range segments {
+		segments[i] = mark + strings.Replace(segment, mark, mark+mark, -1) + mark
+	}
+
+	return strings.Join(segments, ".")
+}
@@ -0,0 +1,246 @@
+package sqlstore
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+// builder writes a SQL statement, binding arguments using the dialect's
+// placeholders
+type builder struct {
+	strings.Builder
+	dialect Dialect
+	args    []interface{}
+}
+
+func (s *Store) newBuilder() *builder {
+	return &builder{dialect: s.Dialect, args: []interface{}{}}
+}
+
+func (b *builder) write(parts ...string) {
+	for _, part := range parts {
+		b.WriteString(part)
+	}
+}
+
+// bind adds an argument and returns its placeholder
+func (b *builder) bind(value interface{}) string {
+	b.args = append(b.args, value)
+	return b.dialect.Placeholder(len(b.args))
+}
+
+/*
+maxBindValues is the number of values bound per "IN (...)" list. Lists of ids
+are split into chunks of it, as databases limit the bind parameters of a single
+statement, i.e. to 999 for SQLite before 3.32.
+*/
+const maxBindValues = 500
+
+// chunkIDs splits ids into lists of at most maxBindValues, an empty list results
+// in no chunks so that no statement is run for it
+func chunkIDs(ids []string) [][]string {
+	chunks := [][]string{}
+	for len(ids) > maxBindValues {
+		chunks = append(chunks, ids[:maxBindValues])
+		ids = ids[maxBindValues:]
+	}
+
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+
+	return chunks
+}
+
+// bindList binds each value, returning the comma separated placeholders
+func (b *builder) bindList(values []string) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = b.bind(value)
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+// condition writes a WHERE clause condition
+type condition func(b *builder)
+
+// whereIn matches rows whose column is one of the provided values, no rows match
+// an empty list as "IN ()" isn't valid SQL
+func (s *Store) whereIn(column string, values []string) condition {
+	return func(b *builder) {
+		if len(values) == 0 {
+			b.write("1 = 0")
+			return
+		}
+
+		b.write(s.quote(column), " IN (", b.bindList(values), ")")
+	}
+}
+
+// selectSQL selects the id and attribute columns of the rows matching where
+func (s *Store) selectSQL(where condition) (string, []interface{}) {
+	statement := s.newBuilder()
+	statement.write("SELECT ", s.columnList(""), " FROM ", s.quote(s.Table), " WHERE ")
+	where(statement)
+
+	return statement.String(), statement.args
+}
+
+// insertSQL inserts a row, an empty id is generated by the database
+func (s *Store) insertSQL(id string, values map[string]interface{}) (string, []interface{}) {
+	columns := []string{}
+	placeholders := []string{}
+
+	statement := s.newBuilder()
+
+	if id != "" {
+		columns = append(columns, s.quote(s.IDColumn))
+		placeholders = append(placeholders, statement.bind(id))
+	}
+
+	for _, attribute := range s.attributes() {
+		column := s.Columns[attribute]
+
+		value, isSet := values[column]
+		if !isSet {
+			continue
+		}
+
+		columns = append(columns, s.quote(column))
+		placeholders = append(placeholders, statement.bind(value))
+	}
+
+	statement.write("INSERT INTO ", s.quote(s.Table))
+	if len(columns) == 0 {
+		statement.write(" DEFAULT VALUES")
+	} else {
+		statement.write(" (", strings.Join(columns, ", "), ") VALUES (", strings.Join(placeholders, ", "), ")")
+	}
+
+	if id == "" && s.Dialect.Returning() {
+		statement.write(" RETURNING ", s.quote(s.IDColumn))
+	}
+
+	return statement.String(), statement.args
+}
+
+// updateSQL sets the provided column values of the row with the provided id
+func (s *Store) updateSQL(id string, values map[string]interface{}) (string, []interface{}) {
+	statement := s.newBuilder()
+	statement.write("UPDATE ", s.quote(s.Table), " SET ")
+
+	assignments := []string{}
+	for _, attribute := range s.attributes() {
+		column := s.Columns[attribute]
+
+		value, isSet := values[column]
+		if !isSet {
+			continue
+		}
+
+		assignments = append(assignments, s.quote(column)+" = "+statement.bind(value))
+	}
+
+	statement.write(strings.Join(assignments, ", "), " WHERE ", s.quote(s.IDColumn), " = ", statement.bind(id))
+
+	return statement.String(), statement.args
+}
+
+/*
+listSQL builds the select and count statements of a list query, both statements
+share the same arguments.
+*/
+func (s *Store) listSQL(query *store.Query) (string, string, []interface{}, *jsh.Error) {
+	filters := s.newBuilder()
+
+	conditions := []string{}
+	for _, filter := range []map[string]string{query.Filter, query.Scope} {
+		for _, attribute := range sortedKeys(filter) {
+			column, err := s.column(attribute, "filter["+attribute+"]")
+			if err != nil {
+				return "", "", nil, err
+			}
+
+			values := strings.Split(filter[attribute], ",")
+			conditions = append(conditions, s.quote(column)+" IN ("+filters.bindList(values)+")")
+		}
+	}
+
+	if len(filters.args) > maxBindValues {
+		return "", "", nil, jsh.ParameterError(
+			fmt.Sprintf("No more than %d filter values are supported", maxBindValues),
+			"filter",
+		)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	order := []string{}
+	for _, field := range query.Sort {
+		column, err := s.column(field.Field, "sort")
+		if err != nil {
+			return "", "", nil, err
+		}
+
+		direction := " ASC"
+		if field.Descending {
+			direction = " DESC"
+		}
+		order = append(order, s.quote(column)+direction)
+	}
+	order = append(order, s.quote(s.IDColumn)+" ASC")
+
+	listSQL := "SELECT " + s.columnList("") + " FROM " + s.quote(s.Table) + where +
+		" ORDER BY " + strings.Join(order, ", ")
+
+	if query.Page.Size > 0 {
+		if query.Page.Cursor != "" {
+			offset, err := strconv.Atoi(query.Page.Cursor)
+			if err != nil || offset < 0 {
+				return "", "", nil, jsh.ParameterError("Invalid page cursor", "page[cursor]")
+			}
+		}
+
+		// limits are validated integers, so they are safe to format inline which
+		// keeps the arguments of both statements identical
+		listSQL += fmt.Sprintf(" LIMIT %d OFFSET %d", query.Page.Size, pageOffset(query.Page))
+	}
+
+	countSQL := "SELECT COUNT(*) FROM " + s.quote(s.Table) + where
+
+	return listSQL, countSQL, filters.args, nil
+}
+
+// column returns the column of an attribute, "id" resolves to the id column.
+// Unmapped attributes result in a 400 for the query parameter.
+func (s *Store) column(attribute string, parameter string) (string, *jsh.Error) {
+	if attribute == "id" {
+		return s.IDColumn, nil
+	}
+
+	column, mapped := s.Columns[attribute]
+	if !mapped {
+		return "", jsh.ParameterError(fmt.Sprintf("Unknown attribute '%s'", attribute), parameter)
+	}
+
+	return column, nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
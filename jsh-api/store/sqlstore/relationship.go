@@ -0,0 +1,160 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+/*
+BelongsTo loads a One-To-One relationship through a foreign key column of this
+store's table that references the related table, for use with
+jshapi.Resource.BatchToOne:
+
+	articles.BatchToOne("author", articleStore.BelongsTo(userStore, "author_id"))
+*/
+func (s *Store) BelongsTo(related *Store, column string) store.GetMany {
+	return func(ctx context.Context, ids []string) (map[string]*jsh.Object, error) {
+		relatedIDs := map[string]string{}
+
+		for _, chunk := range chunkIDs(ids) {
+			statement := s.newBuilder()
+			statement.write(
+				"SELECT ", s.quote(s.IDColumn), ", ", s.quote(column),
+				" FROM ", s.quote(s.Table),
+				" WHERE ", s.quote(s.IDColumn), " IN (", statement.bindList(chunk), ")",
+				" AND ", s.quote(column), " IS NOT NULL",
+			)
+
+			err := s.scanRelatedIDs(ctx, statement, relatedIDs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		distinct := []string{}
+		seen := map[string]bool{}
+		for _, relatedID := range relatedIDs {
+			if !seen[relatedID] {
+				seen[relatedID] = true
+				distinct = append(distinct, relatedID)
+			}
+		}
+
+		objects, err := related.getMany(ctx, distinct)
+		if err != nil {
+			return nil, err
+		}
+
+		results := map[string]*jsh.Object{}
+		for id, relatedID := range relatedIDs {
+			if object, exists := objects[relatedID]; exists {
+				results[id] = object
+			}
+		}
+
+		return results, nil
+	}
+}
+
+// scanRelatedIDs runs a statement selecting ids followed by related ids, adding
+// them to relatedIDs
+func (s *Store) scanRelatedIDs(ctx context.Context, statement *builder, relatedIDs map[string]string) error {
+	rows, err := s.DB.QueryContext(ctx, statement.String(), statement.args...)
+	if err != nil {
+		return s.mapError(err, "")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, relatedID interface{}
+
+		err = rows.Scan(&id, &relatedID)
+		if err != nil {
+			return jsh.ISE(fmt.Sprintf("Error scanning '%s' row: %s", s.Type, err))
+		}
+
+		relatedIDs[fmt.Sprintf("%v", normalize(id))] = fmt.Sprintf("%v", normalize(relatedID))
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return s.mapError(err, "")
+	}
+
+	return nil
+}
+
+/*
+HasMany loads a One-To-Many relationship through a foreign key column of the
+related table that references this store's table, for use with
+jshapi.Resource.BatchToMany:
+
+	users.BatchToMany("articles", userStore.HasMany(articleStore, "author_id"))
+*/
+func (s *Store) HasMany(related *Store, foreignKey string) store.ToManyBatch {
+	return func(ctx context.Context, ids []string) (map[string]jsh.List, error) {
+		return related.queryRelated(ctx, ids, func(statement *builder, ids []string) {
+			statement.write(
+				"SELECT ", related.quote(foreignKey), ", ", related.columnList(""),
+				" FROM ", related.quote(related.Table),
+				" WHERE ", related.quote(foreignKey), " IN (", statement.bindList(ids), ")",
+				" ORDER BY ", related.quote(related.IDColumn), " ASC",
+			)
+		})
+	}
+}
+
+/*
+ManyToMany loads a Many-To-Many relationship through a join table, parentColumn
+references this store's table and relatedColumn the related one:
+
+	articles.BatchToMany("tags", articleStore.ManyToMany(tagStore, "article_tags", "article_id", "tag_id"))
+*/
+func (s *Store) ManyToMany(related *Store, joinTable string, parentColumn string, relatedColumn string) store.ToManyBatch {
+	return func(ctx context.Context, ids []string) (map[string]jsh.List, error) {
+		return related.queryRelated(ctx, ids, func(statement *builder, ids []string) {
+			statement.write(
+				"SELECT ", related.quote("j."+parentColumn), ", ", related.columnList("r"),
+				" FROM ", related.quote(related.Table), " r",
+				" JOIN ", related.quote(joinTable), " j",
+				" ON ", related.quote("j."+relatedColumn), " = ", related.quote("r."+related.IDColumn),
+				" WHERE ", related.quote("j."+parentColumn), " IN (", statement.bindList(ids), ")",
+				" ORDER BY ", related.quote("r."+related.IDColumn), " ASC",
+			)
+		})
+	}
+}
+
+/*
+queryRelated runs the statement built for each chunk of the parent ids, which
+selects the parent id followed by the related object's columns, grouping the
+objects by parent id. No statement is run for an empty list of ids.
+*/
+func (s *Store) queryRelated(ctx context.Context, ids []string, build func(statement *builder, ids []string)) (map[string]jsh.List, error) {
+	results := map[string]jsh.List{}
+
+	for _, chunk := range chunkIDs(ids) {
+		statement := s.newBuilder()
+		build(statement, chunk)
+
+		rows, err := s.DB.QueryContext(ctx, statement.String(), statement.args...)
+		if err != nil {
+			return nil, s.mapError(err, "")
+		}
+
+		objects, keys, err := s.scanObjects(rows, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, object := range objects {
+			parentID := keys[i][0]
+			results[parentID] = append(results[parentID], object)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,183 @@
+//go:build sqlite
+
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// sqliteSchema creates the tables used by TestSQLite
+const sqliteSchema = `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email_address TEXT UNIQUE
+);
+CREATE TABLE articles (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	author_id INTEGER REFERENCES users (id)
+);
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	label TEXT NOT NULL
+);
+CREATE TABLE article_tags (
+	article_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL
+);
+INSERT INTO users (name, email_address) VALUES ('Ann', 'ann@example.com'), ('Bob', 'bob@example.com'), ('Cid', NULL);
+INSERT INTO articles (title, author_id) VALUES ('First', 1), ('Second', 1), ('Draft', NULL);
+INSERT INTO tags (label) VALUES ('go'), ('sql');
+INSERT INTO article_tags (article_id, tag_id) VALUES (1, 1), (1, 2), (2, 2);
+`
+
+/*
+TestSQLite runs the Store against an in-memory SQLite database. It requires cgo
+and the mattn/go-sqlite3 driver, run it with:
+
+	go get github.com/mattn/go-sqlite3
+	go test -tags sqlite ./jsh-api/store/sqlstore
+*/
+func TestSQLite(t *testing.T) {
+
+	ctx := context.Background()
+
+	Convey("SQLite Tests", t, func() {
+
+		db, err := sql.Open("sqlite3", ":memory:")
+		So(err, ShouldBeNil)
+
+		// every connection to ":memory:" opens a new database
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec(sqliteSchema)
+		So(err, ShouldBeNil)
+
+		Reset(func() {
+			db.Close()
+		})
+
+		users := New(db, SQLite, "users", "users", map[string]string{
+			"name":  "name",
+			"email": "email_address",
+		})
+		articles := New(db, SQLite, "articles", "articles", map[string]string{"title": "title"})
+		tags := New(db, SQLite, "tags", "tags", map[string]string{"label": "label"})
+
+		attributes := func(object *jsh.Object) map[string]interface{} {
+			values := map[string]interface{}{}
+			So(object.Unmarshal(object.Type, &values), ShouldBeNil)
+			return values
+		}
+
+		Convey("should save and read rows", func() {
+			object, _ := jsh.NewObject("", "users", map[string]string{"name": "Dee", "email": "dee@example.com"})
+
+			saved, err := users.Save(ctx, object)
+			So(err, ShouldBeNil)
+			So(saved.ID, ShouldEqual, "4")
+
+			fetched, err := users.Get(ctx, "4")
+			So(err, ShouldBeNil)
+			So(attributes(fetched), ShouldResemble, map[string]interface{}{
+				"name":  "Dee",
+				"email": "dee@example.com",
+			})
+		})
+
+		Convey("should update only the provided columns", func() {
+			object, _ := jsh.NewObject("2", "users", map[string]string{"name": "Rob"})
+
+			updated, err := users.Update(ctx, object)
+			So(err, ShouldBeNil)
+			So(attributes(updated), ShouldResemble, map[string]interface{}{
+				"name":  "Rob",
+				"email": "bob@example.com",
+			})
+		})
+
+		Convey("should 404 for missing rows", func() {
+			_, err := users.Get(ctx, "9")
+			So(err.(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+
+			object, _ := jsh.NewObject("9", "users", map[string]string{"name": "Nobody"})
+			_, err = users.Update(ctx, object)
+			So(err.(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+
+			So(users.Delete(ctx, "9").(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("should delete rows", func() {
+			So(users.Delete(ctx, "3"), ShouldBeNil)
+
+			_, err := users.Get(ctx, "3")
+			So(err.(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("should map unique violations to a 409", func() {
+			object, _ := jsh.NewObject("", "users", map[string]string{"name": "Ann", "email": "ann@example.com"})
+
+			_, err := users.Save(ctx, object)
+			So(err.(*jsh.Error).Status, ShouldEqual, http.StatusConflict)
+		})
+
+		Convey("should filter, sort and paginate lists", func() {
+			result, err := users.ListQuery(ctx, &store.Query{
+				Filter: map[string]string{"name": "Ann,Bob"},
+				Sort:   []store.SortField{{Field: "name", Descending: true}},
+				Page:   store.Page{Number: 1, Size: 1},
+			})
+			So(err, ShouldBeNil)
+			So(result.Total, ShouldEqual, 2)
+			So(result.TotalKnown, ShouldBeTrue)
+			So(len(result.Objects), ShouldEqual, 1)
+			So(result.Objects[0].ID, ShouldEqual, "2")
+		})
+
+		Convey("should follow cursors", func() {
+			result, err := users.ListQuery(ctx, &store.Query{Page: store.Page{Size: 2, Cursor: "0"}})
+			So(err, ShouldBeNil)
+			So(result.NextCursor, ShouldEqual, "2")
+			So(result.PrevCursor, ShouldEqual, "")
+
+			result, err = users.ListQuery(ctx, &store.Query{Page: store.Page{Size: 2, Cursor: "2"}})
+			So(err, ShouldBeNil)
+			So(len(result.Objects), ShouldEqual, 1)
+			So(result.NextCursor, ShouldEqual, "")
+			So(result.PrevCursor, ShouldEqual, "0")
+		})
+
+		Convey("should trim sparse fieldsets", func() {
+			result, err := users.ListQuery(ctx, &store.Query{Fields: map[string][]string{"users": {"name"}}})
+			So(err, ShouldBeNil)
+			So(len(result.Objects), ShouldEqual, 3)
+			So(attributes(result.Objects[0]), ShouldResemble, map[string]interface{}{"name": "Ann"})
+		})
+
+		Convey("should load relationships", func() {
+			authors, err := articles.BelongsTo(users, "author_id")(ctx, []string{"1", "2", "3"})
+			So(err, ShouldBeNil)
+			So(len(authors), ShouldEqual, 2)
+			So(authors["2"].ID, ShouldEqual, "1")
+
+			written, err := users.HasMany(articles, "author_id")(ctx, []string{"1", "2"})
+			So(err, ShouldBeNil)
+			So(len(written["1"]), ShouldEqual, 2)
+			So(written, ShouldNotContainKey, "2")
+
+			tagged, err := articles.ManyToMany(tags, "article_tags", "article_id", "tag_id")(ctx, []string{"1", "2"})
+			So(err, ShouldBeNil)
+			So(len(tagged["1"]), ShouldEqual, 2)
+			So(tagged["2"][0].ID, ShouldEqual, "2")
+		})
+	})
+}
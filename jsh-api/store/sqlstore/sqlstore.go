@@ -0,0 +1,470 @@
+/*
+Package sqlstore implements store.CRUD on top of database/sql. A Store maps a
+resource type to a table using a declarative attribute to column mapping:
+
+	users := sqlstore.New(db, sqlstore.Postgres, "users", "users", map[string]string{
+		"name":  "name",
+		"email": "email_address",
+	})
+	api.Add(jshapi.NewCRUDResource("users", users))
+
+Lists are filtered, sorted and paginated in SQL using store.Query, see ListQuery.
+Relationships are loaded in batches through foreign keys or join tables, see
+BelongsTo, HasMany and ManyToMany. Missing rows are sent as a 404 and unique
+constraint violations as a 409.
+*/
+package sqlstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+// Store maps a resource type to a database table
+type Store struct {
+	DB      *sql.DB
+	Dialect Dialect
+	// Type is the resource type of the objects stored in Table
+	Type  string
+	Table string
+	// IDColumn holds the resource id, defaults to "id"
+	IDColumn string
+	// Columns maps each attribute to the column it is stored in
+	Columns map[string]string
+	// GenerateID is used to assign ids to saved objects that don't have one. If
+	// not set, the database is expected to generate ids.
+	GenerateID func() string
+}
+
+// New creates a Store for a resource type stored in table
+func New(db *sql.DB, dialect Dialect, resourceType string, table string, columns map[string]string) *Store {
+	return &Store{
+		DB:       db,
+		Dialect:  dialect,
+		Type:     resourceType,
+		Table:    table,
+		IDColumn: "id",
+		Columns:  columns,
+	}
+}
+
+// Save inserts a new row for the object and returns it as stored
+func (s *Store) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	if object.Type != s.Type {
+		return nil, jsh.Conflict(
+			fmt.Sprintf("Type '%s' does not match resource type '%s'", object.Type, s.Type),
+			"",
+		)
+	}
+
+	values, err := s.columnValues(object.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	id := object.ID
+	if id == "" && s.GenerateID != nil {
+		id = s.GenerateID()
+	}
+
+	query, args := s.insertSQL(id, values)
+
+	switch {
+	case id != "":
+		_, err = s.DB.ExecContext(ctx, query, args...)
+	case s.Dialect.Returning():
+		err = s.DB.QueryRowContext(ctx, query, args...).Scan(&id)
+	default:
+		var result sql.Result
+		result, err = s.DB.ExecContext(ctx, query, args...)
+		if err == nil {
+			var lastID int64
+			lastID, err = result.LastInsertId()
+			id = strconv.FormatInt(lastID, 10)
+		}
+	}
+
+	if err != nil {
+		return nil, s.mapError(err, id)
+	}
+
+	return s.Get(ctx, id)
+}
+
+// Get returns the object stored in the row with the provided id
+func (s *Store) Get(ctx context.Context, id string) (*jsh.Object, error) {
+	query, args := s.selectSQL(s.whereIn(s.IDColumn, []string{id}))
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, s.mapError(err, id)
+	}
+
+	objects, _, err := s.scanObjects(rows, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) == 0 {
+		return nil, jsh.NotFound(s.Type, id)
+	}
+
+	return objects[0], nil
+}
+
+// List returns all objects ordered by id
+func (s *Store) List(ctx context.Context) (jsh.List, error) {
+	result, err := s.ListQuery(ctx, &store.Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Objects, nil
+}
+
+/*
+ListQuery translates the query into parameterized SQL:
+
+	filter[name]  becomes "column IN (...)" of the comma separated values, the
+	              same goes for Scope
+	sort          becomes "ORDER BY", followed by the id column
+	page          becomes "LIMIT" and "OFFSET", cursors are offsets
+	fields[type]  trims the attributes of the returned objects
+
+Filtering or sorting by an attribute without a column results in a 400.
+*/
+func (s *Store) ListQuery(ctx context.Context, query *store.Query) (*store.ListResult, error) {
+	listSQL, countSQL, args, queryErr := s.listSQL(query)
+	if queryErr != nil {
+		return nil, queryErr
+	}
+
+	rows, err := s.DB.QueryContext(ctx, listSQL, args...)
+	if err != nil {
+		return nil, s.mapError(err, "")
+	}
+
+	objects, _, err := s.scanObjects(rows, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &store.ListResult{Objects: objects, TotalKnown: true}
+
+	if query.Page.Size > 0 {
+		err = s.DB.QueryRowContext(ctx, countSQL, args...).Scan(&result.Total)
+		if err != nil {
+			return nil, s.mapError(err, "")
+		}
+
+		if query.Page.Cursor != "" {
+			offset := pageOffset(query.Page)
+			if offset > 0 {
+				previous := offset - query.Page.Size
+				if previous < 0 {
+					previous = 0
+				}
+				result.PrevCursor = strconv.Itoa(previous)
+			}
+
+			if offset+len(objects) < result.Total {
+				result.NextCursor = strconv.Itoa(offset + len(objects))
+			}
+		}
+	} else {
+		result.Total = len(objects)
+	}
+
+	fields, sparse := query.Fields[s.Type]
+	if sparse {
+		for _, object := range objects {
+			trimErr := trimAttributes(object, fields)
+			if trimErr != nil {
+				return nil, trimErr
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Update sets the columns of the provided attributes, leaving the others untouched
+func (s *Store) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	values, err := s.columnValues(object.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return s.Get(ctx, object.ID)
+	}
+
+	query, args := s.updateSQL(object.ID, values)
+
+	_, err = s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, s.mapError(err, object.ID)
+	}
+
+	// the affected row count can't tell missing rows apart, as MySQL only counts
+	// rows whose values changed, Get results in a 404 for missing ones instead
+	return s.Get(ctx, object.ID)
+}
+
+// Delete removes the row with the provided id
+func (s *Store) Delete(ctx context.Context, id string) error {
+	statement := s.newBuilder()
+	statement.write("DELETE FROM ", s.quote(s.Table), " WHERE ", s.quote(s.IDColumn), " = ", statement.bind(id))
+
+	result, err := s.DB.ExecContext(ctx, statement.String(), statement.args...)
+	if err != nil {
+		return s.mapError(err, id)
+	}
+
+	// as with updates, the affected row count alone doesn't tell that the row is
+	// missing, Get confirms it with a 404
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		_, err = s.Get(ctx, id)
+		return err
+	}
+
+	return nil
+}
+
+// getMany returns the objects with the provided ids keyed by id
+func (s *Store) getMany(ctx context.Context, ids []string) (map[string]*jsh.Object, error) {
+	objects := map[string]*jsh.Object{}
+
+	for _, chunk := range chunkIDs(ids) {
+		query, args := s.selectSQL(s.whereIn(s.IDColumn, chunk))
+
+		rows, err := s.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, s.mapError(err, "")
+		}
+
+		list, _, err := s.scanObjects(rows, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range list {
+			objects[object.ID] = object
+		}
+	}
+
+	return objects, nil
+}
+
+// attributes returns the mapped attributes in a stable order
+func (s *Store) attributes() []string {
+	attributes := make([]string, 0, len(s.Columns))
+	for attribute := range s.Columns {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+
+	return attributes
+}
+
+// columnValues maps JSON attributes to column values, unknown attributes result
+// in a 422
+func (s *Store) columnValues(raw json.RawMessage) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if len(raw) == 0 {
+		return values, nil
+	}
+
+	attributes := map[string]interface{}{}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&attributes)
+	if err != nil {
+		return nil, jsh.InputError("Attributes must be an object", "attributes")
+	}
+
+	for attribute, value := range attributes {
+		column, mapped := s.Columns[attribute]
+		if !mapped {
+			return nil, jsh.InputError(fmt.Sprintf("Unknown attribute '%s'", attribute), attribute)
+		}
+
+		values[column], err = columnValue(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// columnValue converts a decoded JSON value into a value drivers accept, nested
+// objects and arrays are stored as JSON
+func columnValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer, nil
+		}
+		return value.Float64()
+	case map[string]interface{}, []interface{}:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, jsh.ISE(fmt.Sprintf("Error encoding attribute: %s", err))
+		}
+		return string(raw), nil
+	}
+
+	return value, nil
+}
+
+/*
+scanObjects reads the rows of a select statement, closing them. The first keys
+columns are returned separately for each object, they are used to group related
+objects by their parent's id.
+*/
+func (s *Store) scanObjects(rows *sql.Rows, keys int) (jsh.List, [][]string, error) {
+	defer rows.Close()
+
+	attributes := s.attributes()
+
+	objects := jsh.List{}
+	objectKeys := [][]string{}
+
+	for rows.Next() {
+		values := make([]interface{}, keys+1+len(attributes))
+		pointers := make([]interface{}, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err := rows.Scan(pointers...)
+		if err != nil {
+			return nil, nil, jsh.ISE(fmt.Sprintf("Error scanning '%s' row: %s", s.Type, err))
+		}
+
+		rowKeys := make([]string, keys)
+		for i := 0; i < keys; i++ {
+			rowKeys[i] = fmt.Sprintf("%v", normalize(values[i]))
+		}
+
+		attributeValues := map[string]interface{}{}
+		for i, attribute := range attributes {
+			attributeValues[attribute] = normalize(values[keys+1+i])
+		}
+
+		object, objectErr := jsh.NewObject(fmt.Sprintf("%v", normalize(values[keys])), s.Type, attributeValues)
+		if objectErr != nil {
+			return nil, nil, objectErr
+		}
+
+		objects = append(objects, object)
+		objectKeys = append(objectKeys, rowKeys)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, nil, s.mapError(err, "")
+	}
+
+	return objects, objectKeys, nil
+}
+
+// normalize converts driver values for JSON encoding
+func normalize(value interface{}) interface{} {
+	if raw, isBytes := value.([]byte); isBytes {
+		return string(raw)
+	}
+
+	return value
+}
+
+// mapError sends missing rows as a 404 and unique violations as a 409
+func (s *Store) mapError(err error, id string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return jsh.NotFound(s.Type, id)
+	case s.Dialect.IsUniqueViolation(err):
+		conflict := jsh.Conflict(
+			fmt.Sprintf("A resource of type '%s' with the same unique attributes already exists", s.Type),
+			"",
+		)
+		conflict.Err = err
+		return conflict
+	}
+
+	return err
+}
+
+// trimAttributes only keeps the requested fields of an object's attributes
+func trimAttributes(object *jsh.Object, fields []string) error {
+	attributes := map[string]json.RawMessage{}
+
+	err := json.Unmarshal(object.Attributes, &attributes)
+	if err != nil {
+		return jsh.ISE(fmt.Sprintf("Error decoding attributes: %s", err))
+	}
+
+	trimmed := map[string]json.RawMessage{}
+	for _, field := range fields {
+		if value, exists := attributes[field]; exists {
+			trimmed[field] = value
+		}
+	}
+
+	raw, err := json.Marshal(trimmed)
+	if err != nil {
+		return jsh.ISE(fmt.Sprintf("Error encoding attributes: %s", err))
+	}
+
+	object.Attributes = raw
+	return nil
+}
+
+func pageOffset(page store.Page) int {
+	if page.Cursor != "" {
+		offset, _ := strconv.Atoi(page.Cursor)
+		return offset
+	}
+
+	if page.Number > 1 {
+		return (page.Number - 1) * page.Size
+	}
+
+	return 0
+}
+
+func (s *Store) quote(identifier string) string {
+	return s.Dialect.Quote(identifier)
+}
+
+// columnList quotes the id column followed by the attribute columns, optionally
+// qualified by a table alias
+func (s *Store) columnList(alias string) string {
+	columns := []string{s.IDColumn}
+	for _, attribute := range s.attributes() {
+		columns = append(columns, s.Columns[attribute])
+	}
+
+	for i, column := range columns {
+		if alias != "" {
+			column = alias + "." + column
+		}
+		columns[i] = s.quote(column)
+	}
+
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,295 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSQL(t *testing.T) {
+
+	newStore := func(dialect Dialect) *Store {
+		return New(nil, dialect, "users", "users", map[string]string{
+			"name":  "name",
+			"email": "email_address",
+		})
+	}
+
+	Convey("SQL Generation Tests", t, func() {
+
+		Convey("should select by id", func() {
+			users := newStore(Postgres)
+
+			query, args := users.selectSQL(users.whereIn("id", []string{"1", "2"}))
+			So(query, ShouldEqual, `SELECT "id", "email_address", "name" FROM "users" WHERE "id" IN ($1, $2)`)
+			So(args, ShouldResemble, []interface{}{"1", "2"})
+		})
+
+		Convey("should insert rows", func() {
+			values := map[string]interface{}{"name": "Ann", "email_address": "ann@example.com"}
+
+			query, args := newStore(Postgres).insertSQL("", values)
+			So(query, ShouldEqual, `INSERT INTO "users" ("email_address", "name") VALUES ($1, $2) RETURNING "id"`)
+			So(args, ShouldResemble, []interface{}{"ann@example.com", "Ann"})
+
+			query, args = newStore(MySQL).insertSQL("7", values)
+			So(query, ShouldEqual, "INSERT INTO `users` (`id`, `email_address`, `name`) VALUES (?, ?, ?)")
+			So(args, ShouldResemble, []interface{}{"7", "ann@example.com", "Ann"})
+		})
+
+		Convey("should update provided columns", func() {
+			query, args := newStore(SQLite).updateSQL("7", map[string]interface{}{"name": "Ann"})
+			So(query, ShouldEqual, `UPDATE "users" SET "name" = ? WHERE "id" = ?`)
+			So(args, ShouldResemble, []interface{}{"Ann", "7"})
+		})
+
+		Convey("should translate list queries", func() {
+			listSQL, countSQL, args, err := newStore(Postgres).listSQL(&store.Query{
+				Filter: map[string]string{"name": "Ann,Bob", "email": "ann@example.com"},
+				Sort:   []store.SortField{{Field: "name", Descending: true}},
+				Page:   store.Page{Number: 3, Size: 10},
+			})
+			So(err, ShouldBeNil)
+			So(listSQL, ShouldEqual, `SELECT "id", "email_address", "name" FROM "users"`+
+				` WHERE "email_address" IN ($1) AND "name" IN ($2, $3)`+
+				` ORDER BY "name" DESC, "id" ASC LIMIT 10 OFFSET 20`)
+			So(countSQL, ShouldEqual, `SELECT COUNT(*) FROM "users" WHERE "email_address" IN ($1) AND "name" IN ($2, $3)`)
+			So(args, ShouldResemble, []interface{}{"ann@example.com", "Ann", "Bob"})
+		})
+
+		Convey("should reject unmapped query attributes", func() {
+			_, _, _, err := newStore(SQLite).listSQL(&store.Query{
+				Sort: []store.SortField{{Field: "password"}},
+			})
+			So(err.Status, ShouldEqual, http.StatusBadRequest)
+			So(err.Source.Parameter, ShouldEqual, "sort")
+
+			_, _, _, err = newStore(SQLite).listSQL(&store.Query{
+				Filter: map[string]string{"password": "secret"},
+			})
+			So(err.Source.Parameter, ShouldEqual, "filter[password]")
+		})
+
+		Convey("should load relationships through join tables", func() {
+			tags := New(nil, SQLite, "tags", "tags", map[string]string{"label": "label"})
+			recorder := &recordingDriver{}
+			tags.DB = sql.OpenDB(recorder)
+
+			articles := New(tags.DB, SQLite, "articles", "articles", map[string]string{})
+
+			_, err := articles.ManyToMany(tags, "article_tags", "article_id", "tag_id")(
+				context.Background(),
+				[]string{"1", "2"},
+			)
+			So(err, ShouldBeNil)
+			So(recorder.queries, ShouldResemble, []string{
+				`SELECT "j"."article_id", "r"."id", "r"."label" FROM "tags" r JOIN "article_tags" j` +
+					` ON "j"."tag_id" = "r"."id" WHERE "j"."article_id" IN (?, ?) ORDER BY "r"."id" ASC`,
+			})
+		})
+
+		Convey("should not query empty lists of ids", func() {
+			tags := New(nil, SQLite, "tags", "tags", map[string]string{"label": "label"})
+			recorder := &recordingDriver{}
+			tags.DB = sql.OpenDB(recorder)
+
+			related, err := tags.HasMany(tags, "parent_id")(context.Background(), []string{})
+			So(err, ShouldBeNil)
+			So(related, ShouldBeEmpty)
+
+			objects, err := tags.BelongsTo(tags, "parent_id")(context.Background(), []string{})
+			So(err, ShouldBeNil)
+			So(objects, ShouldBeEmpty)
+
+			So(recorder.queries, ShouldBeEmpty)
+
+			query, args := tags.selectSQL(tags.whereIn("id", []string{}))
+			So(query, ShouldEqual, `SELECT "id", "label" FROM "tags" WHERE 1 = 0`)
+			So(args, ShouldBeEmpty)
+		})
+
+		Convey("should split long lists of ids", func() {
+			tags := New(nil, SQLite, "tags", "tags", map[string]string{"label": "label"})
+			recorder := &recordingDriver{}
+			tags.DB = sql.OpenDB(recorder)
+
+			ids := make([]string, 2*maxBindValues+1)
+			for i := range ids {
+				ids[i] = strconv.Itoa(i)
+			}
+
+			_, err := tags.HasMany(tags, "parent_id")(context.Background(), ids)
+			So(err, ShouldBeNil)
+			So(len(recorder.queries), ShouldEqual, 3)
+
+			_, _, _, queryErr := tags.listSQL(&store.Query{
+				Filter: map[string]string{"label": strings.Join(ids, ",")},
+			})
+			So(queryErr.Status, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("should quote identifiers", func() {
+			So(SQLite.Quote(`we"ird`), ShouldEqual, `"we""ird"`)
+			So(MySQL.Quote("users.id"), ShouldEqual, "`users`.`id`")
+		})
+	})
+}
+
+func TestStore(t *testing.T) {
+
+	ctx := context.Background()
+
+	Convey("Store Tests", t, func() {
+
+		recorder := &recordingDriver{}
+		users := New(sql.OpenDB(recorder), SQLite, "users", "users", map[string]string{"name": "name"})
+
+		Convey("should 404 for missing rows", func() {
+			_, err := users.Get(ctx, "9")
+			So(err.(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+
+			recorder.affected = 0
+			So(users.Delete(ctx, "9").(*jsh.Error).Status, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("should update rows whose values didn't change", func() {
+			recorder.affected = 0
+			recorder.rows = [][]driver.Value{{int64(1), []byte("Ann")}}
+
+			object, _ := jsh.NewObject("1", "users", map[string]string{"name": "Ann"})
+			updated, err := users.Update(ctx, object)
+			So(err, ShouldBeNil)
+			So(updated.ID, ShouldEqual, "1")
+		})
+
+		Convey("should read rows into objects", func() {
+			recorder.rows = [][]driver.Value{{int64(1), []byte("Ann")}}
+
+			object, err := users.Get(ctx, "1")
+			So(err, ShouldBeNil)
+			So(object.ID, ShouldEqual, "1")
+			So(string(object.Attributes), ShouldContainSubstring, `"name": "Ann"`)
+		})
+
+		Convey("should map unique violations to a 409", func() {
+			recorder.err = errors.New("UNIQUE constraint failed: users.name")
+
+			object, _ := jsh.NewObject("", "users", map[string]string{"name": "Ann"})
+			_, err := users.Save(ctx, object)
+			So(err.(*jsh.Error).Status, ShouldEqual, http.StatusConflict)
+		})
+
+		Convey("should reject unknown attributes", func() {
+			object, _ := jsh.NewObject("", "users", map[string]string{"password": "secret"})
+			_, err := users.Save(ctx, object)
+			So(err.(*jsh.Error).Status, ShouldEqual, 422)
+		})
+	})
+}
+
+/*
+recordingDriver is a database/sql driver that records queries, answers every
+query with its rows and every exec with its affected row count, or fails with
+err if set.
+*/
+type recordingDriver struct {
+	queries  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+func (d *recordingDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+func (d *recordingDriver) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type recordingStmt struct {
+	driver *recordingDriver
+	query  string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.queries = append(s.driver.queries, s.query)
+	if s.driver.err != nil {
+		return nil, s.driver.err
+	}
+
+	return driver.RowsAffected(s.driver.affected), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.queries = append(s.driver.queries, s.query)
+	if s.driver.err != nil {
+		return nil, s.driver.err
+	}
+
+	return &recordingRows{rows: s.driver.rows}, nil
+}
+
+type recordingRows struct {
+	rows [][]driver.Value
+}
+
+func (r *recordingRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{"id", "name"}
+	}
+
+	columns := make([]string, len(r.rows[0]))
+	for i := range columns {
+		columns[i] = "column"
+	}
+	return columns
+}
+
+func (r *recordingRows) Close() error {
+	return nil
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -1,37 +1,98 @@
 // Package store is a collection of composable interfaces that are can be implemented
-// in order to build a storage driver
+// in order to build a storage driver.
+//
+// Storage functions return plain Go errors. jsh.ErrorType values are sent as is,
+// anything else is converted via the jsh.RegisterErrorMapper registry, falling
+// back to an Internal Server Error.
 package store
 
 import (
 	"context"
+	"errors"
 
 	"github.com/derekdowling/go-json-spec-handler"
 )
 
 // CRUD implements all sub-storage functions
 type CRUD interface {
-	Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType)
-	Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType)
-	List(ctx context.Context) (jsh.List, jsh.ErrorType)
-	Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType)
-	Delete(ctx context.Context, id string) jsh.ErrorType
+	Save(ctx context.Context, object *jsh.Object) (*jsh.Object, error)
+	Get(ctx context.Context, id string) (*jsh.Object, error)
+	List(ctx context.Context) (jsh.List, error)
+	Update(ctx context.Context, object *jsh.Object) (*jsh.Object, error)
+	Delete(ctx context.Context, id string) error
+}
+
+/*
+ErrVersionMismatch should be returned (or wrapped) by VersionedUpdate and
+VersionedDelete implementations when the expected version no longer matches the
+stored one. jshapi responds to it with a 412 Precondition Failed.
+*/
+var ErrVersionMismatch = errors.New("version mismatch")
+
+/*
+VersionedUpdate is implemented by storage that supports optimistic concurrency.
+The expected version is taken from the request's If-Match header, it is "" when
+no precondition was provided and "*" when any existing version is acceptable.
+Storage should expose the current version of objects via their
+meta.version (jsh.MetaVersion) so that clients are sent an ETag to match against.
+*/
+type VersionedUpdate interface {
+	UpdateVersion(ctx context.Context, object *jsh.Object, version string) (*jsh.Object, error)
+}
+
+// VersionedDelete deletes an object only if it matches the expected version, see
+// VersionedUpdate
+type VersionedDelete interface {
+	DeleteVersion(ctx context.Context, id string, version string) error
 }
 
 // Save a new resource to storage
-type Save func(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType)
+type Save func(ctx context.Context, object *jsh.Object) (*jsh.Object, error)
 
 // Get a specific instance of a resource by id from storage
-type Get func(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType)
+type Get func(ctx context.Context, id string) (*jsh.Object, error)
 
 // List all instances of a resource from storage
-type List func(ctx context.Context) (jsh.List, jsh.ErrorType)
+type List func(ctx context.Context) (jsh.List, error)
 
 // Update an existing object in storage
-type Update func(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType)
+type Update func(ctx context.Context, object *jsh.Object) (*jsh.Object, error)
 
 // Delete an object from storage by id
-type Delete func(ctx context.Context, id string) jsh.ErrorType
+type Delete func(ctx context.Context, id string) error
+
+// Action performs a custom action on the resource with the provided id, or on the
+// whole collection if id is "". The request document is nil for requests without
+// a body.
+type Action func(ctx context.Context, id string, request *jsh.Document) (jsh.Sendable, error)
 
 // ToMany retrieves a list of objects of a single resource type that are related to
 // the provided resource id
-type ToMany func(ctx context.Context, id string) (jsh.List, jsh.ErrorType)
+type ToMany func(ctx context.Context, id string) (jsh.List, error)
+
+/*
+GetMany retrieves the objects for many ids at once, keyed by id. Ids without an
+object are omitted from the result. When registered as a One-To-One relationship,
+the ids are those of the parent resources and the objects are the related ones.
+*/
+type GetMany func(ctx context.Context, ids []string) (map[string]*jsh.Object, error)
+
+// ToManyBatch retrieves the related objects of many resources at once, keyed by
+// the id of the parent resource
+type ToManyBatch func(ctx context.Context, ids []string) (map[string]jsh.List, error)
+
+// SetToOne replaces the to-one relationship of the resource with the provided id
+// and returns the resulting linkage. A nil identifier clears the relationship.
+type SetToOne func(ctx context.Context, id string, related *jsh.ResourceIdentifier) (*jsh.ResourceIdentifier, error)
+
+// AddToMany adds members to a to-many relationship of the resource with the
+// provided id and returns the full resulting linkage
+type AddToMany func(ctx context.Context, id string, related jsh.ResourceLinkage) (jsh.ResourceLinkage, error)
+
+// RemoveFromMany removes members from a to-many relationship of the resource with
+// the provided id and returns the full resulting linkage
+type RemoveFromMany func(ctx context.Context, id string, related jsh.ResourceLinkage) (jsh.ResourceLinkage, error)
+
+// ReplaceToMany replaces all members of a to-many relationship of the resource
+// with the provided id and returns the resulting linkage
+type ReplaceToMany func(ctx context.Context, id string, related jsh.ResourceLinkage) (jsh.ResourceLinkage, error)
@@ -0,0 +1,20 @@
+package store
+
+import "context"
+
+/*
+TypedCRUD is the typed counterpart of CRUD, used via jshapi.NewTypedResource.
+Implementations receive and return plain Go values, jshapi takes care of
+decoding, validating and encoding the JSON API objects. T must be a struct, or a
+pointer to one, with a string field holding the id: either tagged `jsh:"id"` or
+named ID.
+*/
+type TypedCRUD[T any] interface {
+	// Save stores a new value, assigning its id unless the client provided one
+	Save(ctx context.Context, value T) (T, error)
+	Get(ctx context.Context, id string) (T, error)
+	List(ctx context.Context) ([]T, error)
+	// Update receives the stored value with the request's attributes applied
+	Update(ctx context.Context, value T) (T, error)
+	Delete(ctx context.Context, id string) error
+}
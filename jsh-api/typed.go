@@ -0,0 +1,299 @@
+package jshapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/jsh-api/store"
+)
+
+/*
+NewTypedResource generates a CRUD resource for storage that works with Go values
+instead of JSON API objects:
+
+	type User struct {
+		ID   string `json:"-"`
+		Name string `json:"name" valid:"required"`
+	}
+
+	resource := jshapi.NewTypedResource[*User]("users", userStorage)
+
+Request attributes are decoded into T and validated, failures are sent as a 422
+listing every invalid attribute. PATCH requests apply the provided attributes to
+the stored value before passing it to storage. The id field of T is set from the
+request and sent as the object's id, never as an attribute, requests setting it
+as an attribute are rejected with a 422.
+*/
+func NewTypedResource[T any](resourceType string, storage store.TypedCRUD[T]) *Resource {
+	return NewCRUDResource(resourceType, newTypedStorage(resourceType, storage))
+}
+
+// typedStorage adapts TypedCRUD storage to store.CRUD
+type typedStorage[T any] struct {
+	resourceType string
+	storage      store.TypedCRUD[T]
+	id           typedID
+}
+
+func newTypedStorage[T any](resourceType string, storage store.TypedCRUD[T]) *typedStorage[T] {
+	return &typedStorage[T]{
+		resourceType: resourceType,
+		storage:      storage,
+		id:           findTypedID(reflect.TypeOf((*T)(nil)).Elem()),
+	}
+}
+
+func (t *typedStorage[T]) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	value := newTypedValue[T]()
+
+	err := t.decode(object, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, storageErr := t.storage.Save(ctx, value)
+	if err := jsh.MapError(storageErr); err != nil {
+		return nil, err
+	}
+
+	return t.encode(saved)
+}
+
+func (t *typedStorage[T]) Get(ctx context.Context, id string) (*jsh.Object, error) {
+	value, storageErr := t.storage.Get(ctx, id)
+	if err := jsh.MapError(storageErr); err != nil {
+		return nil, err
+	}
+
+	return t.encode(value)
+}
+
+func (t *typedStorage[T]) List(ctx context.Context) (jsh.List, error) {
+	values, storageErr := t.storage.List(ctx)
+	if err := jsh.MapError(storageErr); err != nil {
+		return nil, err
+	}
+
+	list := jsh.List{}
+	for _, value := range values {
+		object, err := t.encode(value)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, object)
+	}
+
+	return list, nil
+}
+
+func (t *typedStorage[T]) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, error) {
+	stored, storageErr := t.storage.Get(ctx, object.ID)
+	if err := jsh.MapError(storageErr); err != nil {
+		return nil, err
+	}
+
+	value := copyTypedValue(stored)
+
+	err := t.decode(object, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, storageErr := t.storage.Update(ctx, value)
+	if err := jsh.MapError(storageErr); err != nil {
+		return nil, err
+	}
+
+	return t.encode(updated)
+}
+
+func (t *typedStorage[T]) Delete(ctx context.Context, id string) error {
+	if err := jsh.MapError(t.storage.Delete(ctx, id)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// decode applies the object's attributes and id to value, validating the result
+func (t *typedStorage[T]) decode(object *jsh.Object, value *T) error {
+	if len(object.Attributes) == 0 {
+		object.Attributes = json.RawMessage("{}")
+	}
+
+	err := t.checkIDAttribute(object)
+	if err != nil {
+		return err
+	}
+
+	// pointer values are decoded into directly so that the validator sees the
+	// struct rather than a pointer to a pointer
+	var target interface{} = value
+	if reflect.TypeOf(value).Elem().Kind() == reflect.Ptr {
+		target = *value
+	}
+
+	errors := object.Unmarshal(t.resourceType, target)
+	if errors != nil {
+		return errors
+	}
+
+	if object.ID != "" {
+		t.id.set(reflect.ValueOf(value).Elem(), object.ID)
+	}
+
+	return nil
+}
+
+/*
+checkIDAttribute rejects attributes that would set the id field of T, which is
+only ever set from the object's id. Names are matched case-insensitively, as
+encoding/json decodes them that way.
+*/
+func (t *typedStorage[T]) checkIDAttribute(object *jsh.Object) error {
+	if t.id.attribute == "" {
+		return nil
+	}
+
+	attributes := map[string]json.RawMessage{}
+
+	// attributes that aren't an object are reported by Unmarshal
+	if json.Unmarshal(object.Attributes, &attributes) != nil {
+		return nil
+	}
+
+	for name := range attributes {
+		if strings.EqualFold(name, t.id.attribute) {
+			return jsh.InputError(fmt.Sprintf("Attribute '%s' is the resource's id and cannot be set", name), name)
+		}
+	}
+
+	return nil
+}
+
+// encode builds the JSON API object of a value, the id field is left out of the
+// attributes
+func (t *typedStorage[T]) encode(value T) (*jsh.Object, error) {
+	reflected := reflect.ValueOf(&value).Elem()
+	if reflected.Kind() == reflect.Ptr && reflected.IsNil() {
+		return nil, jsh.ISE(fmt.Sprintf("Storage returned a nil '%s'", t.resourceType))
+	}
+
+	object, err := jsh.NewObject(t.id.get(reflected), t.resourceType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.id.attribute != "" {
+		attributes := map[string]json.RawMessage{}
+
+		jsonErr := json.Unmarshal(object.Attributes, &attributes)
+		if jsonErr != nil {
+			return nil, jsh.ISE(fmt.Sprintf("Error decoding attributes of '%s': %s", t.resourceType, jsonErr))
+		}
+
+		// the builtin delete is shadowed by the package's method constant
+		trimmed := map[string]json.RawMessage{}
+		for name, attribute := range attributes {
+			if name != t.id.attribute {
+				trimmed[name] = attribute
+			}
+		}
+
+		err = object.Marshal(trimmed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return object, nil
+}
+
+// newTypedValue returns the zero value of T, allocating the struct if T is a
+// pointer
+func newTypedValue[T any]() T {
+	var value T
+
+	valueType := reflect.TypeOf(&value).Elem()
+	if valueType.Kind() == reflect.Ptr {
+		reflect.ValueOf(&value).Elem().Set(reflect.New(valueType.Elem()))
+	}
+
+	return value
+}
+
+// copyTypedValue copies the struct a pointer value points to, so that storage
+// doesn't see changes to values it returned
+func copyTypedValue[T any](value T) T {
+	reflected := reflect.ValueOf(&value).Elem()
+	if reflected.Kind() != reflect.Ptr || reflected.IsNil() {
+		return value
+	}
+
+	copied := reflect.New(reflected.Type().Elem())
+	copied.Elem().Set(reflected.Elem())
+
+	return copied.Interface().(T)
+}
+
+// typedID locates the id field of a typed resource
+type typedID struct {
+	index []int
+	// attribute is the JSON name the id field is encoded with, if any
+	attribute string
+}
+
+// findTypedID finds the string field tagged `jsh:"id"`, or named ID, of a struct
+// or pointer to a struct. Panics if there is none, as the resource can't work.
+func findTypedID(valueType reflect.Type) typedID {
+	if valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	if valueType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("jshapi: typed resource %s must be a struct", valueType))
+	}
+
+	field, found := reflect.StructField{}, false
+	for i := 0; i < valueType.NumField(); i++ {
+		candidate := valueType.Field(i)
+		if candidate.Tag.Get("jsh") == "id" {
+			field, found = candidate, true
+			break
+		}
+
+		if candidate.Name == "ID" {
+			field, found = candidate, true
+		}
+	}
+
+	if !found || field.Type.Kind() != reflect.String {
+		panic(fmt.Sprintf("jshapi: typed resource %s needs a string ID field", valueType))
+	}
+
+	id := typedID{index: field.Index}
+
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+	case "":
+		id.attribute = field.Name
+	default:
+		id.attribute = tag
+	}
+
+	return id
+}
+
+func (id typedID) get(value reflect.Value) string {
+	return reflect.Indirect(value).FieldByIndex(id.index).String()
+}
+
+func (id typedID) set(value reflect.Value, identifier string) {
+	reflect.Indirect(value).FieldByIndex(id.index).SetString(identifier)
+}
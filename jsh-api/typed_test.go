@@ -0,0 +1,153 @@
+package jshapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/go-json-spec-handler/client"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type typedUser struct {
+	Key   string `json:"key" jsh:"id"`
+	Name  string `json:"name" valid:"required"`
+	Email string `json:"email" valid:"email"`
+}
+
+// typedUserStorage is a minimal store.TypedCRUD implementation
+type typedUserStorage struct {
+	users map[string]*typedUser
+}
+
+func (s *typedUserStorage) Save(ctx context.Context, user *typedUser) (*typedUser, error) {
+	if user.Key == "" {
+		user.Key = strconv.Itoa(len(s.users) + 1)
+	}
+	s.users[user.Key] = user
+	return user, nil
+}
+
+func (s *typedUserStorage) Get(ctx context.Context, id string) (*typedUser, error) {
+	user, exists := s.users[id]
+	if !exists {
+		return nil, jsh.NotFound("users", id)
+	}
+	return user, nil
+}
+
+func (s *typedUserStorage) List(ctx context.Context) ([]*typedUser, error) {
+	users := []*typedUser{}
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (s *typedUserStorage) Update(ctx context.Context, user *typedUser) (*typedUser, error) {
+	s.users[user.Key] = user
+
+	// a typed nil error, as returned by storage using *jsh.Error results
+	var err *jsh.Error
+	return user, err
+}
+
+func (s *typedUserStorage) Delete(ctx context.Context, id string) error {
+	if _, exists := s.users[id]; !exists {
+		return jsh.NotFound("users", id)
+	}
+	return nil
+}
+
+func TestTypedResource(t *testing.T) {
+
+	storage := &typedUserStorage{users: map[string]*typedUser{}}
+
+	api := New("")
+	api.Add(NewTypedResource[*typedUser]("users", storage))
+
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	Convey("Typed Resource Tests", t, func() {
+
+		Reset(func() {
+			storage.users = map[string]*typedUser{}
+		})
+
+		Convey("should save decoded values and encode the result", func() {
+			object, _ := jsh.NewObject("", "users", map[string]string{"name": "Ann", "email": "ann@example.com"})
+
+			doc, resp, err := jsc.Post(server.URL, object)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusCreated)
+			So(doc.First().ID, ShouldEqual, "1")
+			So(storage.users["1"], ShouldResemble, &typedUser{Key: "1", Name: "Ann", Email: "ann@example.com"})
+
+			attributes := map[string]string{}
+			So(doc.First().Unmarshal("users", &attributes), ShouldBeNil)
+			So(attributes, ShouldNotContainKey, "key")
+		})
+
+		Convey("should reject ids set through attributes", func() {
+			object, _ := jsh.NewObject("", "users", map[string]string{"name": "Ann", "Key": "admin"})
+
+			doc, resp, err := jsc.Post(server.URL, object)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 422)
+			So(doc.Errors[0].Source.Pointer, ShouldEqual, "/data/attributes/key")
+			So(storage.users, ShouldBeEmpty)
+
+			storage.users["7"] = &typedUser{Key: "7", Name: "Ann"}
+
+			object, _ = jsh.NewObject("7", "users", map[string]string{"key": "8"})
+
+			_, resp, err = jsc.Patch(server.URL, object)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 422)
+		})
+
+		Convey("should send every validation error", func() {
+			object, _ := jsh.NewObject("", "users", map[string]string{"email": "invalid"})
+
+			doc, resp, err := jsc.Post(server.URL, object)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 422)
+			So(len(doc.Errors), ShouldEqual, 2)
+		})
+
+		Convey("should apply patches to the stored value", func() {
+			storage.users["7"] = &typedUser{Key: "7", Name: "Ann", Email: "ann@example.com"}
+
+			object, _ := jsh.NewObject("7", "users", map[string]string{"name": "Bob"})
+
+			doc, resp, err := jsc.Patch(server.URL, object)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			So(doc.First().ID, ShouldEqual, "7")
+			So(storage.users["7"], ShouldResemble, &typedUser{Key: "7", Name: "Bob", Email: "ann@example.com"})
+		})
+
+		Convey("should fetch and list encoded values", func() {
+			storage.users["7"] = &typedUser{Key: "7", Name: "Ann"}
+
+			doc, _, err := jsc.Fetch(server.URL, "users", "7")
+			So(err, ShouldBeNil)
+			So(doc.First().ID, ShouldEqual, "7")
+
+			doc, _, err = jsc.List(server.URL, "users")
+			So(err, ShouldBeNil)
+			So(len(doc.Data), ShouldEqual, 1)
+		})
+	})
+
+	Convey("->findTypedID()", t, func() {
+
+		Convey("should require a string id field", func() {
+			So(func() { NewTypedResource[struct{ Name string }]("users", nil) }, ShouldPanic)
+		})
+	})
+}
@@ -6,6 +6,11 @@ import "encoding/json"
 type Links struct {
 	Self    *Link `json:"self,omitempty"`
 	Related *Link `json:"related,omitempty"`
+	// First, Last, Prev and Next are pagination links
+	First *Link `json:"first,omitempty"`
+	Last  *Link `json:"last,omitempty"`
+	Prev  *Link `json:"prev,omitempty"`
+	Next  *Link `json:"next,omitempty"`
 }
 
 // Link is a resource link that can encode as a string or as an object
@@ -0,0 +1,330 @@
+package jsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the language that jsh builds its errors in
+const DefaultLanguage = "en"
+
+/*
+Messages is the MessageCatalog used by SendDocument to localize error responses
+based on the request's Accept-Language header. Load your translations into it
+at startup:
+
+	err := jsh.Messages.LoadFile("fr", "locales/fr.json")
+*/
+var Messages = NewMessageCatalog()
+
+/*
+Message is the localized Title and Detail for a specific error code. Detail may
+reference any of the error's Params via "{name}", i.e. "No '{type}' with ID {id}".
+Empty fields are left untranslated.
+*/
+type Message struct {
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+/*
+MessageCatalog holds localized error messages keyed by language tag and then by
+error code. Errors are always created in the catalog's DefaultLanguage, so they
+are only rewritten when a different language is negotiated.
+*/
+type MessageCatalog struct {
+	// DefaultLanguage is the language errors are created in
+	DefaultLanguage string
+	lock            sync.RWMutex
+	languages       map[string]map[string]Message
+}
+
+/*
+NewMessageCatalog creates a catalog containing the default English messages for
+all errors that jsh builds.
+*/
+func NewMessageCatalog() *MessageCatalog {
+	catalog := &MessageCatalog{
+		DefaultLanguage: DefaultLanguage,
+		languages:       map[string]map[string]Message{},
+	}
+
+	catalog.AddMessages(DefaultLanguage, map[string]Message{
+		CodeInternalServerError: {Title: DefaultErrorTitle, Detail: DefaultErrorDetail},
+		CodeInvalidAttribute:    {Title: "Invalid Attribute"},
+		CodeSpecificationError:  {Title: "JSON API Specification Error"},
+		CodeNotFound: {
+			Title:  "Not Found",
+			Detail: "No resource of type '{type}' exists for ID: {id}",
+		},
+		CodePreconditionFailed:   {Title: "Precondition Failed"},
+		CodePreconditionRequired: {Title: "Precondition Required"},
+		CodeRouteNotFound: {
+			Title:  "Not Found",
+			Detail: "No route exists for path: {path}",
+		},
+		CodeInvalidParameter:    {Title: "Invalid Query Parameter"},
+		CodeConflict:            {Title: "Conflict"},
+		CodeForbidden:           {Title: "Forbidden"},
+		CodeInvalidRelationship: {Title: "Invalid Relationship"},
+		CodeMethodNotAllowed: {
+			Title:  "Method Not Allowed",
+			Detail: "Method {method} is not supported by this route",
+		},
+	})
+
+	return catalog
+}
+
+// AddMessages registers messages for a language, overriding existing codes
+func (c *MessageCatalog) AddMessages(language string, messages map[string]Message) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	language = strings.ToLower(language)
+
+	existing, exists := c.languages[language]
+	if !exists {
+		existing = map[string]Message{}
+		c.languages[language] = existing
+	}
+
+	for code, message := range messages {
+		existing[code] = message
+	}
+}
+
+/*
+Load reads a JSON message file for a language. The file is an object keyed by
+error code:
+
+	{
+		"not_found": {
+			"title": "Introuvable",
+			"detail": "Aucune ressource de type '{type}' avec l'ID {id}"
+		}
+	}
+*/
+func (c *MessageCatalog) Load(language string, reader io.Reader) error {
+	messages := map[string]Message{}
+
+	err := json.NewDecoder(reader).Decode(&messages)
+	if err != nil {
+		return fmt.Errorf("Error parsing messages for language '%s': %s", language, err.Error())
+	}
+
+	c.AddMessages(language, messages)
+	return nil
+}
+
+// LoadFile loads a JSON message file for a language, see Load for the format
+func (c *MessageCatalog) LoadFile(language string, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return c.Load(language, file)
+}
+
+/*
+LoadDir loads every "<language>.json" file within dir, i.e. "fr.json" or
+"pt-br.json".
+*/
+func (c *MessageCatalog) LoadDir(dir string) error {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		language := strings.TrimSuffix(filepath.Base(filename), ".json")
+
+		err = c.LoadFile(language, filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Message returns the message registered for a language and error code
+func (c *MessageCatalog) Message(language string, code string) (Message, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	message, exists := c.languages[strings.ToLower(language)][code]
+	return message, exists
+}
+
+/*
+Negotiate picks the best supported language for an Accept-Language header value,
+i.e. "fr-CA,fr;q=0.9,en;q=0.8". A regional tag falls back to its primary language
+when only the latter is available. Returns the DefaultLanguage if nothing matches.
+*/
+func (c *MessageCatalog) Negotiate(acceptLanguage string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+
+		if _, exists := c.languages[tag]; exists {
+			return tag
+		}
+
+		primary := strings.SplitN(tag, "-", 2)[0]
+		if _, exists := c.languages[primary]; exists {
+			return primary
+		}
+	}
+
+	return c.DefaultLanguage
+}
+
+/*
+Localize returns a copy of the error translated into the specified language. The
+original error is returned when the language is the DefaultLanguage, or no
+message exists for the error's code. A Detail is only translated when it is
+empty or the DefaultLanguage's one, so that details specific to the error, such
+as an InputError's message, are kept.
+*/
+func (c *MessageCatalog) Localize(language string, err *Error) *Error {
+	localized, _ := c.localize(language, err)
+	return localized
+}
+
+// LocalizeList localizes every error in the list, see Localize
+func (c *MessageCatalog) LocalizeList(language string, errors ErrorList) ErrorList {
+	localized, _ := c.localizeList(language, errors)
+	return localized
+}
+
+// localize implements Localize, reporting whether a translation was applied
+func (c *MessageCatalog) localize(language string, err *Error) (*Error, bool) {
+	if err.Code == "" || strings.EqualFold(language, c.DefaultLanguage) {
+		return err, false
+	}
+
+	message, exists := c.Message(language, err.Code)
+	if !exists {
+		return err, false
+	}
+
+	localized := *err
+	applied := false
+
+	if message.Title != "" && message.Title != err.Title {
+		localized.Title = message.Title
+		applied = true
+	}
+
+	if message.Detail != "" && c.defaultDetail(err) {
+		detail := formatMessage(message.Detail, err.Params)
+		if detail != err.Detail {
+			localized.Detail = detail
+			applied = true
+		}
+	}
+
+	if !applied {
+		return err, false
+	}
+
+	return &localized, true
+}
+
+// localizeList implements LocalizeList, reporting whether any translation was
+// applied
+func (c *MessageCatalog) localizeList(language string, errors ErrorList) (ErrorList, bool) {
+	localized := make(ErrorList, len(errors))
+	applied := false
+
+	for i, err := range errors {
+		var translated bool
+		localized[i], translated = c.localize(language, err)
+		applied = applied || translated
+	}
+
+	return localized, applied
+}
+
+// defaultDetail reports whether an error's Detail is empty or the one the
+// DefaultLanguage defines for its code
+func (c *MessageCatalog) defaultDetail(err *Error) bool {
+	if err.Detail == "" {
+		return true
+	}
+
+	message, exists := c.Message(c.DefaultLanguage, err.Code)
+	return exists && message.Detail != "" && formatMessage(message.Detail, err.Params) == err.Detail
+}
+
+// formatMessage substitutes "{name}" placeholders with params
+func formatMessage(message string, params map[string]string) string {
+	for name, value := range params {
+		message = strings.Replace(message, "{"+name+"}", value, -1)
+	}
+
+	return message
+}
+
+// parseAcceptLanguage returns the lowercased language tags from an Accept-Language
+// header ordered by descending quality
+func parseAcceptLanguage(header string) []string {
+	type weightedTag struct {
+		tag     string
+		quality float64
+	}
+
+	tags := []weightedTag{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		tags = append(tags, weightedTag{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	ordered := make([]string, len(tags))
+	for i, weighted := range tags {
+		ordered[i] = weighted.tag
+	}
+
+	return ordered
+}
@@ -0,0 +1,106 @@
+package jsh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMessages(t *testing.T) {
+
+	Convey("Message Catalog Tests", t, func() {
+
+		catalog := NewMessageCatalog()
+		loadErr := catalog.Load("fr", strings.NewReader(`{
+			"not_found": {
+				"title": "Introuvable",
+				"detail": "Aucune ressource de type '{type}' avec l'ID {id}"
+			},
+			"invalid_attribute": {
+				"title": "Attribut invalide",
+				"detail": "L'attribut '{attribute}' est invalide"
+			}
+		}`))
+		So(loadErr, ShouldBeNil)
+
+		Convey("->Negotiate()", func() {
+
+			Convey("should pick the highest quality supported language", func() {
+				So(catalog.Negotiate("de;q=0.9, fr;q=0.8, en;q=0.5"), ShouldEqual, "fr")
+			})
+
+			Convey("should fall back to the primary language subtag", func() {
+				So(catalog.Negotiate("fr-CA"), ShouldEqual, "fr")
+			})
+
+			Convey("should default to english", func() {
+				So(catalog.Negotiate(""), ShouldEqual, DefaultLanguage)
+				So(catalog.Negotiate("de"), ShouldEqual, DefaultLanguage)
+				So(catalog.Negotiate("fr;q=0"), ShouldEqual, DefaultLanguage)
+			})
+		})
+
+		Convey("->Localize()", func() {
+			err := NotFound("user", "1")
+
+			Convey("should translate using error params", func() {
+				localized := catalog.Localize("fr", err)
+				So(localized.Title, ShouldEqual, "Introuvable")
+				So(localized.Detail, ShouldEqual, "Aucune ressource de type 'user' avec l'ID 1")
+				So(err.Title, ShouldEqual, "Not Found")
+			})
+
+			Convey("should keep details specific to the error", func() {
+				input := InputError("Name is too short", "name")
+				localized := catalog.Localize("fr", input)
+				So(localized.Title, ShouldEqual, "Attribut invalide")
+				So(localized.Detail, ShouldEqual, "Name is too short")
+
+				input.Detail = ""
+				So(catalog.Localize("fr", input).Detail, ShouldEqual, "L'attribut 'name' est invalide")
+			})
+
+			Convey("should leave errors without a translation untouched", func() {
+				ise := ISE("boom")
+				So(catalog.Localize("fr", ise), ShouldEqual, ise)
+				So(catalog.Localize(DefaultLanguage, err), ShouldEqual, err)
+			})
+		})
+
+		Convey("->SendDocument()", func() {
+			Messages = catalog
+			Reset(func() {
+				Messages = NewMessageCatalog()
+			})
+
+			request := &http.Request{Header: http.Header{}}
+			request.Header.Set("Accept-Language", "fr-FR,fr;q=0.9")
+			writer := httptest.NewRecorder()
+
+			sendErr := Send(writer, request, NotFound("user", "1"))
+			So(sendErr, ShouldBeNil)
+			So(writer.Code, ShouldEqual, http.StatusNotFound)
+			So(writer.Header().Get("Content-Language"), ShouldEqual, "fr")
+
+			doc := &struct {
+				Errors []*Error `json:"errors"`
+			}{}
+			So(json.Unmarshal(writer.Body.Bytes(), doc), ShouldBeNil)
+			So(doc.Errors[0].Title, ShouldEqual, "Introuvable")
+			So(writer.Header().Get("Vary"), ShouldEqual, "Accept-Language")
+
+			Convey("should only set Content-Language when a translation was applied", func() {
+				writer := httptest.NewRecorder()
+
+				sendErr := Send(writer, request, ISE("boom"))
+				So(sendErr, ShouldBeNil)
+				So(writer.Header().Get("Content-Language"), ShouldBeEmpty)
+				So(writer.Header().Get("Vary"), ShouldEqual, "Accept-Language")
+			})
+		})
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 )
@@ -19,6 +21,10 @@ type Object struct {
 	// Status is the HTTP Status Code that should be associated with the object
 	// when it is sent.
 	Status int `json:"-"`
+	// ETag is the entity tag associated with the object. When sent, it is used as
+	// the response's ETag. jsc sets it from the response the object was fetched
+	// with so that subsequent updates can be made conditional via If-Match.
+	ETag string `json:"-"`
 }
 
 // NewObject prepares a new JSON Object for an API response. Whatever is provided
@@ -146,7 +152,19 @@ func (o *Object) Validate(r *http.Request, response bool) *Error {
 
 		o.Status = http.StatusOK
 		break
-	case "GET":
+	case "DELETE":
+		acceptable := map[int]bool{200: true, 202: true, 204: true}
+
+		if o.Status != 0 {
+			if _, validCode := acceptable[o.Status]; !validCode {
+				return SpecificationError("DELETE Status must be one of 200, 202, or 204.")
+			}
+			break
+		}
+
+		o.Status = http.StatusOK
+		break
+	case "GET", "HEAD":
 		o.Status = http.StatusOK
 		break
 	// If we hit this it means someone is attempting to use an unsupported HTTP
@@ -161,6 +179,54 @@ func (o *Object) Validate(r *http.Request, response bool) *Error {
 	return nil
 }
 
+/*
+Version returns the resource version stored under Meta["version"] if set. The
+version is used as the object's ETag when it is sent.
+*/
+func (o *Object) Version() string {
+	version, exists := o.Meta[MetaVersion]
+	if !exists || version == nil {
+		return ""
+	}
+
+	switch v := version.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+/*
+LastModified returns the timestamp stored under Meta["lastModified"] if set. The
+timestamp can either be a time.Time, an RFC 3339 formatted string, or a number of
+seconds since the Unix epoch.
+*/
+func (o *Object) LastModified() (time.Time, bool) {
+	switch timestamp := o.Meta[MetaLastModified].(type) {
+	case time.Time:
+		return timestamp, !timestamp.IsZero()
+	case *time.Time:
+		if timestamp == nil {
+			return time.Time{}, false
+		}
+		return *timestamp, !timestamp.IsZero()
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, timestamp)
+		return parsed, err == nil
+	case float64:
+		return time.Unix(int64(timestamp), 0), true
+	case int64:
+		return time.Unix(timestamp, 0), true
+	case int:
+		return time.Unix(int64(timestamp), 0), true
+	}
+
+	return time.Time{}, false
+}
+
 // String prints a formatted string representation of the object
 func (o *Object) String() string {
 	raw, err := json.MarshalIndent(o, "", " ")
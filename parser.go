@@ -43,7 +43,12 @@ as part of your full flow.
 	}
 */
 func ParseObject(r *http.Request) (*Object, *Error) {
-	document, err := ParseDoc(r, ObjectMode)
+	return DefaultConfig().ParseObject(r)
+}
+
+// ParseObject works like the package level ParseObject, using the Config's settings
+func (c *Config) ParseObject(r *http.Request) (*Object, *Error) {
+	document, err := c.ParseDoc(r, ObjectMode)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +70,12 @@ ParseList validates the HTTP request and returns a resulting list of objects
 parsed from the request Body. Use just like ParseObject.
 */
 func ParseList(r *http.Request) (List, *Error) {
-	document, err := ParseDoc(r, ListMode)
+	return DefaultConfig().ParseList(r)
+}
+
+// ParseList works like the package level ParseList, using the Config's settings
+func (c *Config) ParseList(r *http.Request) (List, *Error) {
+	document, err := c.ParseDoc(r, ListMode)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +92,12 @@ ParseDoc parses and returns a top level jsh.Document. In most cases, using
 "ParseList" or "ParseObject" is preferable.
 */
 func ParseDoc(r *http.Request, mode DocumentMode) (*Document, *Error) {
-	return NewParser(r).Document(r.Body, mode)
+	return DefaultConfig().ParseDoc(r, mode)
+}
+
+// ParseDoc works like the package level ParseDoc, using the Config's settings
+func (c *Config) ParseDoc(r *http.Request, mode DocumentMode) (*Document, *Error) {
+	return c.NewParser(r).Document(r.Body, mode)
 }
 
 // Parser is an abstraction layer that helps to support parsing JSON payload from
@@ -90,13 +105,21 @@ func ParseDoc(r *http.Request, mode DocumentMode) (*Document, *Error) {
 type Parser struct {
 	Method  string
 	Headers http.Header
+	// Config to parse with, uses DefaultConfig() if nil
+	Config *Config
 }
 
 // NewParser creates a parser from an http.Request
 func NewParser(request *http.Request) *Parser {
+	return DefaultConfig().NewParser(request)
+}
+
+// NewParser creates a parser from an http.Request that uses the Config's settings
+func (c *Config) NewParser(request *http.Request) *Parser {
 	return &Parser{
 		Method:  request.Method,
 		Headers: request.Header,
+		Config:  c,
 	}
 }
 
@@ -117,11 +140,32 @@ func (p *Parser) Document(payload io.ReadCloser, mode DocumentMode) (*Document,
 		Mode: mode,
 	}
 
-	decodeErr := json.NewDecoder(io.LimitReader(payload, MaxContentLength)).Decode(document)
+	config := p.Config
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	content := json.RawMessage{}
+	decodeErr := json.NewDecoder(io.LimitReader(payload, config.maxContentLength())).Decode(&content)
+	if decodeErr == nil {
+		decodeErr = json.Unmarshal(content, document)
+	}
 	if decodeErr != nil {
 		return nil, ISE(fmt.Sprintf("Error parsing JSON Document: %s", decodeErr.Error()))
 	}
 
+	// resource linkage is held separately from data objects
+	if mode == LinkageMode {
+		linkage, linkageErr := parseLinkage(content)
+		if linkageErr != nil {
+			return nil, linkageErr
+		}
+
+		document.Linkage = linkage
+		document.Data = List{}
+		return document, nil
+	}
+
 	// If the document has data, validate against specification
 	if document.HasData() {
 		for _, object := range document.Data {
@@ -54,7 +54,7 @@ func TestParsing(t *testing.T) {
 				So(object.Type, ShouldEqual, "user")
 				So(object.ID, ShouldEqual, "sweetID123")
 				So(object.Attributes, ShouldResemble, json.RawMessage(`{"ID":"123"}`))
-				So(object.Relationships["company"], ShouldResemble, &Relationship{Data: ResourceLinkage{&ResourceIdentifier{Type: "company", ID: "companyID123"}}})
+				So(object.Relationships["company"], ShouldResemble, &Relationship{Data: ResourceLinkage{&ResourceIdentifier{Type: "company", ID: "companyID123"}}, ToOne: true})
 				So(object.Relationships["comments"], ShouldResemble, &Relationship{Data: ResourceLinkage{{Type: "comments", ID: "commentID123"}, {Type: "comments", ID: "commentID456"}}})
 			})
 
@@ -127,5 +127,35 @@ func TestParsing(t *testing.T) {
 				So(err.Source.Pointer, ShouldEqual, "/data/attributes/id")
 			})
 		})
+
+		Convey("->ParseDoc()", func() {
+
+			Convey("should parse resource linkage in LinkageMode", func() {
+				linkageJSON := `{
+					"links": {"self": "/articles/1/relationships/author"},
+					"data": {"type": "people", "id": "9", "meta": {"primary": true}}
+				}`
+
+				req, reqErr := testRequest([]byte(linkageJSON))
+				So(reqErr, ShouldBeNil)
+
+				doc, err := ParseDoc(req, LinkageMode)
+				So(err, ShouldBeNil)
+				So(doc.HasData(), ShouldBeFalse)
+				So(doc.Links.Self.HREF, ShouldEqual, "/articles/1/relationships/author")
+				So(doc.Linkage.ToOne, ShouldBeTrue)
+				So(doc.Linkage.Data[0].ID, ShouldEqual, "9")
+				So(doc.Linkage.Data[0].Meta["primary"], ShouldEqual, true)
+			})
+
+			Convey("should validate resource linkage in LinkageMode", func() {
+				req, reqErr := testRequest([]byte(`{"data": [{"type": "people"}]}`))
+				So(reqErr, ShouldBeNil)
+
+				_, err := ParseDoc(req, LinkageMode)
+				So(err, ShouldNotBeNil)
+				So(err.Code, ShouldEqual, CodeSpecificationError)
+			})
+		})
 	})
 }
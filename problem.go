@@ -0,0 +1,198 @@
+package jsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ProblemContentType is the media type of an RFC 7807 problem details document
+const ProblemContentType = "application/problem+json"
+
+/*
+Problem represents an RFC 7807 problem details object: https://tools.ietf.org/html/rfc7807
+
+Problems can be converted to and from an Error in order to interoperate with
+services that don't speak JSON API:
+
+	problem := jsh.NewProblem(jsh.NotFound("user", "1"))
+	err := problem.ToError()
+
+When converting, the problem "type" maps to the error's "about" link, "instance"
+maps to the error ID, and extension members map to the error's meta. An error's
+source pointer is carried via the "pointer" extension member.
+*/
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions holds any additional members of the problem object
+	Extensions map[string]interface{}
+}
+
+// problemPointerMember is the extension member used to carry Error.Source.Pointer
+const problemPointerMember = "pointer"
+
+/*
+NewProblem converts a JSON API Error into an RFC 7807 Problem.
+*/
+func NewProblem(err *Error) *Problem {
+	problem := &Problem{
+		Title:    err.Title,
+		Status:   err.Status,
+		Detail:   err.Detail,
+		Instance: err.ID,
+	}
+
+	about, hasAbout := err.Links["about"]
+	if hasAbout && about != nil {
+		problem.Type = about.HREF
+	}
+
+	if len(err.Meta) > 0 || err.Source.Pointer != "" {
+		problem.Extensions = map[string]interface{}{}
+		for key, value := range err.Meta {
+			problem.Extensions[key] = value
+		}
+
+		if err.Source.Pointer != "" {
+			problem.Extensions[problemPointerMember] = err.Source.Pointer
+		}
+	}
+
+	return problem
+}
+
+/*
+ToError converts the Problem into a JSON API Error. A problem type of "about:blank"
+is treated as unset as per RFC 7807.
+*/
+func (p *Problem) ToError() *Error {
+	err := &Error{
+		ID:     p.Instance,
+		Title:  p.Title,
+		Detail: p.Detail,
+		Status: p.Status,
+	}
+
+	if p.Type != "" && p.Type != "about:blank" {
+		err.Links = map[string]*Link{"about": NewLink(p.Type)}
+	}
+
+	for key, value := range p.Extensions {
+		pointer, isString := value.(string)
+		if key == problemPointerMember && isString {
+			err.Source.Pointer = pointer
+			continue
+		}
+
+		if err.Meta == nil {
+			err.Meta = map[string]interface{}{}
+		}
+		err.Meta[key] = value
+	}
+
+	return err
+}
+
+/*
+MarshalJSON flattens extension members into the top level of the problem object
+as required by RFC 7807.
+*/
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := map[string]interface{}{}
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+
+	if p.Type != "" {
+		members["type"] = p.Type
+	}
+	if p.Title != "" {
+		members["title"] = p.Title
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+
+	return json.Marshal(members)
+}
+
+/*
+UnmarshalJSON parses a problem object, collecting any unknown members as
+extensions. A "status" encoded as a string is tolerated.
+*/
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	members := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+
+	problem := Problem{}
+	for key, raw := range members {
+		var target interface{}
+
+		switch key {
+		case "type":
+			target = &problem.Type
+		case "title":
+			target = &problem.Title
+		case "detail":
+			target = &problem.Detail
+		case "instance":
+			target = &problem.Instance
+		case "status":
+			problem.Status, err = parseProblemStatus(raw)
+			if err != nil {
+				return err
+			}
+			continue
+		default:
+			var extension interface{}
+			err = json.Unmarshal(raw, &extension)
+			if err != nil {
+				return err
+			}
+
+			if problem.Extensions == nil {
+				problem.Extensions = map[string]interface{}{}
+			}
+			problem.Extensions[key] = extension
+			continue
+		}
+
+		err = json.Unmarshal(raw, target)
+		if err != nil {
+			return fmt.Errorf("Invalid problem member '%s': %s", key, err.Error())
+		}
+	}
+
+	*p = problem
+	return nil
+}
+
+// parseProblemStatus accepts either a JSON number or a numeric string
+func parseProblemStatus(raw json.RawMessage) (int, error) {
+	var status int
+	err := json.Unmarshal(raw, &status)
+	if err == nil {
+		return status, nil
+	}
+
+	var statusStr string
+	err = json.Unmarshal(raw, &statusStr)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid problem status: %s", string(raw))
+	}
+
+	return strconv.Atoi(statusStr)
+}
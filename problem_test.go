@@ -0,0 +1,75 @@
+package jsh
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProblem(t *testing.T) {
+
+	Convey("Problem Tests", t, func() {
+
+		problemJSON := `{
+			"type": "https://example.com/probs/out-of-credit",
+			"title": "You do not have enough credit.",
+			"status": 403,
+			"detail": "Your current balance is 30, but that costs 50.",
+			"instance": "/account/12345/msgs/abc",
+			"balance": 30
+		}`
+
+		Convey("->UnmarshalJSON()", func() {
+			problem := &Problem{}
+			err := json.Unmarshal([]byte(problemJSON), problem)
+
+			So(err, ShouldBeNil)
+			So(problem.Type, ShouldEqual, "https://example.com/probs/out-of-credit")
+			So(problem.Status, ShouldEqual, http.StatusForbidden)
+			So(problem.Instance, ShouldEqual, "/account/12345/msgs/abc")
+			So(problem.Extensions["balance"], ShouldEqual, 30)
+		})
+
+		Convey("should tolerate a string status", func() {
+			problem := &Problem{}
+			err := json.Unmarshal([]byte(`{"title": "Gone", "status": "410"}`), problem)
+
+			So(err, ShouldBeNil)
+			So(problem.Status, ShouldEqual, http.StatusGone)
+		})
+
+		Convey("->ToError()", func() {
+			problem := &Problem{}
+			So(json.Unmarshal([]byte(problemJSON), problem), ShouldBeNil)
+
+			err := problem.ToError()
+			So(err.Status, ShouldEqual, http.StatusForbidden)
+			So(err.Title, ShouldEqual, problem.Title)
+			So(err.Detail, ShouldEqual, problem.Detail)
+			So(err.ID, ShouldEqual, problem.Instance)
+			So(err.Links["about"].HREF, ShouldEqual, problem.Type)
+			So(err.Meta["balance"], ShouldEqual, 30)
+			So(err.Validate(&http.Request{}, true), ShouldBeNil)
+		})
+
+		Convey("->NewProblem()", func() {
+			err := InputError("Name is required", "name")
+			problem := NewProblem(err)
+
+			So(problem.Status, ShouldEqual, 422)
+			So(problem.Title, ShouldEqual, err.Title)
+			So(problem.Extensions["pointer"], ShouldEqual, "/data/attributes/name")
+
+			Convey("should round trip back into an Error", func() {
+				raw, jsonErr := json.Marshal(problem)
+				So(jsonErr, ShouldBeNil)
+
+				parsed := &Problem{}
+				So(json.Unmarshal(raw, parsed), ShouldBeNil)
+				So(parsed.ToError().Source.Pointer, ShouldEqual, err.Source.Pointer)
+			})
+		})
+	})
+}
@@ -1,17 +1,25 @@
 package jsh
 
 import (
+	"bytes"
 	"fmt"
+	"net/http"
 
 	"encoding/json"
 )
 
 // Relationship represents a reference from the resource object in which it's
 // defined to other resource objects.
+//
+// A Relationship is also Sendable, it is sent as a resource linkage document in
+// response to a request for a relationship URL, i.e. "/articles/1/relationships/author".
 type Relationship struct {
 	Links *Links                 `json:"links,omitempty"`
 	Data  ResourceLinkage        `json:"data,omitempty"`
 	Meta  map[string]interface{} `json:"meta,omitempty"`
+	// ToOne causes Data to be encoded as a single resource identifier, or null
+	// when empty, rather than an array. It is set when parsing such linkage.
+	ToOne bool `json:"-"`
 }
 
 // ResourceLinkage is a typedef around a slice of resource identifiers. This
@@ -20,8 +28,114 @@ type ResourceLinkage []*ResourceIdentifier
 
 // ResourceIdentifier identifies an individual resource.
 type ResourceIdentifier struct {
-	Type string `json:"type" valid:"required"`
-	ID   string `json:"id" valid:"required"`
+	Type string                 `json:"type" valid:"required"`
+	ID   string                 `json:"id" valid:"required"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+/*
+Validate ensures that the relationship is a valid resource linkage, allowing it
+to be sent as a document.
+*/
+func (r *Relationship) Validate(request *http.Request, response bool) *Error {
+	if r.ToOne && len(r.Data) > 1 {
+		return ISE("A to-one relationship cannot contain more than one resource identifier")
+	}
+
+	for _, identifier := range r.Data {
+		if identifier == nil || identifier.Type == "" || identifier.ID == "" {
+			return ISE("Resource identifiers must contain both 'type' and 'id'")
+		}
+	}
+
+	return nil
+}
+
+/*
+MarshalJSON encodes the linkage of a to-one relationship as a single resource
+identifier or null, and that of a to-many relationship as an array.
+*/
+func (r *Relationship) MarshalJSON() ([]byte, error) {
+	type MarshalRelationship Relationship
+
+	if !r.ToOne {
+		return json.Marshal(MarshalRelationship(*r))
+	}
+
+	return json.Marshal(struct {
+		MarshalRelationship
+		Data interface{} `json:"data"`
+	}{
+		MarshalRelationship: MarshalRelationship(*r),
+		Data:                r.linkageData(),
+	})
+}
+
+// linkageData returns the value the relationship's data is encoded as
+func (r *Relationship) linkageData() interface{} {
+	if r.ToOne {
+		if len(r.Data) == 0 {
+			return nil
+		}
+
+		return r.Data[0]
+	}
+
+	if r.Data == nil {
+		return ResourceLinkage{}
+	}
+
+	return r.Data
+}
+
+/*
+UnmarshalJSON decodes a relationship, marking it as ToOne if its data is a
+single resource identifier or null.
+*/
+func (r *Relationship) UnmarshalJSON(data []byte) error {
+	type UnmarshalRelationship Relationship
+
+	relationship := struct {
+		UnmarshalRelationship
+		Data json.RawMessage `json:"data"`
+	}{}
+
+	err := json.Unmarshal(data, &relationship)
+	if err != nil {
+		return err
+	}
+
+	*r = Relationship(relationship.UnmarshalRelationship)
+
+	raw := bytes.TrimSpace(relationship.Data)
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if raw[0] == '{' || bytes.Equal(raw, []byte("null")) {
+		r.ToOne = true
+	}
+
+	if bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	return json.Unmarshal(raw, &r.Data)
+}
+
+// Identifier returns the resource identifier of the object
+func (o *Object) Identifier() *ResourceIdentifier {
+	return &ResourceIdentifier{Type: o.Type, ID: o.ID}
+}
+
+// Linkage returns the resource identifiers of all objects in the list
+func (list List) Linkage() ResourceLinkage {
+	linkage := ResourceLinkage{}
+	for _, object := range list {
+		linkage = append(linkage, object.Identifier())
+	}
+
+	return linkage
 }
 
 /*
@@ -51,3 +165,66 @@ func (rl *ResourceLinkage) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+/*
+ParseRelationship parses the resource linkage from the body of a request to a
+relationship URL, i.e. "PATCH /articles/1/relationships/author":
+
+	{"data": {"type": "people", "id": "12"}}
+
+The data member may be a single resource identifier, an array of them, or null
+which results in an empty linkage.
+*/
+func ParseRelationship(r *http.Request) (ResourceLinkage, *Error) {
+	return DefaultConfig().ParseRelationship(r)
+}
+
+// ParseRelationship works like the package level ParseRelationship, using the
+// Config's settings. Use ParseDoc in LinkageMode to also find out whether the
+// data was a single resource identifier, see Relationship.ToOne.
+func (c *Config) ParseRelationship(r *http.Request) (ResourceLinkage, *Error) {
+	document, err := c.ParseDoc(r, LinkageMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return document.Linkage.Data, nil
+}
+
+/*
+parseLinkage decodes and validates the resource linkage of a relationship
+document. The data member may be a single resource identifier, an array of them,
+or null which results in an empty linkage.
+*/
+func parseLinkage(content []byte) (*Relationship, *Error) {
+	// decode members individually, a null "data" must be told apart from a
+	// missing one
+	members := map[string]json.RawMessage{}
+
+	decodeErr := json.Unmarshal(content, &members)
+	if decodeErr != nil {
+		return nil, ISE(fmt.Sprintf("Error parsing JSON Document: %s", decodeErr.Error()))
+	}
+
+	if _, hasData := members["data"]; !hasData {
+		return nil, SpecificationError("Relationship document must contain a 'data' member")
+	}
+
+	linkage := &Relationship{}
+	decodeErr = json.Unmarshal(content, linkage)
+	if decodeErr != nil {
+		return nil, SpecificationError(fmt.Sprintf("Invalid resource linkage: %s", decodeErr.Error()))
+	}
+
+	for _, identifier := range linkage.Data {
+		if identifier == nil || identifier.Type == "" || identifier.ID == "" {
+			return nil, SpecificationError("Resource identifiers must contain both 'type' and 'id'")
+		}
+	}
+
+	if linkage.Data == nil {
+		linkage.Data = ResourceLinkage{}
+	}
+
+	return linkage, nil
+}
@@ -1,6 +1,8 @@
 package jsh
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -33,3 +35,47 @@ func TestRelationship(t *testing.T) {
 		})
 	})
 }
+
+func TestParseRelationship(t *testing.T) {
+
+	Convey("ParseRelationship Tests", t, func() {
+
+		request := func(body string) *http.Request {
+			req, err := http.NewRequest("PATCH", "/articles/1/relationships/author", strings.NewReader(body))
+			So(err, ShouldBeNil)
+			req.Header.Set("Content-Type", ContentType)
+			return req
+		}
+
+		Convey("should parse a single resource identifier", func() {
+			linkage, err := ParseRelationship(request(`{"data": {"type": "people", "id": "12"}}`))
+			So(err, ShouldBeNil)
+			So(len(linkage), ShouldEqual, 1)
+			So(linkage[0].ID, ShouldEqual, "12")
+		})
+
+		Convey("should parse a list of resource identifiers", func() {
+			linkage, err := ParseRelationship(request(`{"data": [{"type": "tags", "id": "2"}, {"type": "tags", "id": "3"}]}`))
+			So(err, ShouldBeNil)
+			So(len(linkage), ShouldEqual, 2)
+		})
+
+		Convey("should parse null data as an empty linkage", func() {
+			linkage, err := ParseRelationship(request(`{"data": null}`))
+			So(err, ShouldBeNil)
+			So(linkage, ShouldBeEmpty)
+		})
+
+		Convey("should reject documents without data", func() {
+			_, err := ParseRelationship(request(`{"meta": {}}`))
+			So(err, ShouldNotBeNil)
+			So(err.Code, ShouldEqual, CodeSpecificationError)
+		})
+
+		Convey("should reject incomplete resource identifiers", func() {
+			_, err := ParseRelationship(request(`{"data": {"type": "people"}}`))
+			So(err, ShouldNotBeNil)
+			So(err.Code, ShouldEqual, CodeSpecificationError)
+		})
+	})
+}
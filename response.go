@@ -20,6 +20,11 @@ type Sendable interface {
 // Send will return a JSON payload to the requestor. If the payload response validation
 // fails, it will send an appropriate error to the requestor and will return the error
 func Send(w http.ResponseWriter, r *http.Request, payload Sendable) *Error {
+	return DefaultConfig().Send(w, r, payload)
+}
+
+// Send works like the package level Send, using the Config's settings
+func (c *Config) Send(w http.ResponseWriter, r *http.Request, payload Sendable) *Error {
 
 	validationErr := payload.Validate(r, true)
 	if validationErr != nil {
@@ -28,14 +33,14 @@ func Send(w http.ResponseWriter, r *http.Request, payload Sendable) *Error {
 		// wrong
 		err := validationErr.Validate(r, true)
 		if err != nil {
-			http.Error(w, DefaultErrorTitle, http.StatusInternalServerError)
+			http.Error(w, c.errorTitle(), http.StatusInternalServerError)
 			return err
 		}
 
 		payload = validationErr
 	}
 
-	return SendDocument(w, r, Build(payload))
+	return c.SendDocument(w, r, c.Build(payload))
 }
 
 /*
@@ -45,8 +50,17 @@ require custom validation or additional build steps before sending.
 SendJSON is designed to always send a response, but will also return the last
 error it encountered to help with debugging in the event of an Internal Server
 Error.
+
+Errors are localized via the Messages catalog using the request's Accept-Language
+header, Content-Language is set when a translation was applied. Successful GET responses carry ETag and Last-Modified validators when
+available, and conditional requests are answered with 304 Not Modified.
 */
 func SendDocument(w http.ResponseWriter, r *http.Request, document *Document) *Error {
+	return DefaultConfig().SendDocument(w, r, document)
+}
+
+// SendDocument works like the package level SendDocument, using the Config's settings
+func (c *Config) SendDocument(w http.ResponseWriter, r *http.Request, document *Document) *Error {
 
 	validationErr := document.Validate(r, true)
 	if validationErr != nil {
@@ -54,24 +68,52 @@ func SendDocument(w http.ResponseWriter, r *http.Request, document *Document) *E
 
 		// If we ever hit this, something seriously wrong has happened
 		if prepErr != nil {
-			http.Error(w, DefaultErrorTitle, http.StatusInternalServerError)
+			http.Error(w, c.errorTitle(), http.StatusInternalServerError)
 			return prepErr
 		}
 
 		// if we didn't error out, make this the new response
-		document = Build(validationErr)
+		document = c.Build(validationErr)
+	}
+
+	if !c.IncludeJSONAPIVersion {
+		document.JSONAPI = nil
+	}
+
+	// apply error presentation settings, and translate errors into the language
+	// the client prefers
+	if document.HasErrors() {
+		if c.Messages != nil {
+			w.Header().Add("Vary", "Accept-Language")
+		}
+
+		var language string
+		document.Errors, language = c.prepareErrors(r.Header.Get("Accept-Language"), document.Errors)
+		if language != "" {
+			w.Header().Set("Content-Language", language)
+		}
 	}
 
 	content, jsonErr := json.MarshalIndent(document, "", " ")
 	if jsonErr != nil {
-		http.Error(w, DefaultErrorTitle, http.StatusInternalServerError)
+		http.Error(w, c.errorTitle(), http.StatusInternalServerError)
 		return ISE(fmt.Sprintf("Unable to marshal JSON payload: %s", jsonErr.Error()))
 	}
 
+	// respond with 304 Not Modified if the client already has this document
+	if c.notModified(w, r, document, content) {
+		w.WriteHeader(http.StatusNotModified)
+		return validationErr
+	}
+
 	w.Header().Add("Content-Type", ContentType)
 	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
 	w.WriteHeader(document.Status)
-	w.Write(content)
+
+	// HEAD responses carry the same headers as GET, but no body
+	if r.Method != "HEAD" {
+		w.Write(content)
+	}
 
 	return validationErr
 }
@@ -40,6 +40,17 @@ func TestSend(t *testing.T) {
 					So(contentLength, ShouldBeGreaterThan, 0)
 					So(writer.HeaderMap.Get("Content-Type"), ShouldEqual, ContentType)
 				})
+
+				Convey("should send headers without a body for HEAD requests", func() {
+
+					request.Method = "HEAD"
+
+					err := Send(writer, request, object)
+					So(err, ShouldBeNil)
+					So(writer.Code, ShouldEqual, http.StatusOK)
+					So(writer.HeaderMap.Get("Content-Length"), ShouldNotBeEmpty)
+					So(writer.Body.Len(), ShouldEqual, 0)
+				})
 			})
 		})
 